@@ -0,0 +1,101 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Command tkbtf-gen generates Go types and decoders for the records of the trace events defined in kprobe_events
+// files, e.g. as written from the built probes with NewKprobeEvent, one file per kernel variant. It is meant to be
+// run with go generate, e.g.
+//
+//	//go:generate go run github.com/elastic/tk-btf/cmd/tkbtf-gen -o events_gen.go linux-5.10=5.10.kprobe_events linux-6.1=6.1.kprobe_events
+//
+// Every argument is a kprobe_events file, optionally prefixed with the name of its variant and an equal sign. When
+// the name is omitted, the variant is named after the file without its extension.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tkbtf "github.com/elastic/tk-btf"
+	"github.com/elastic/tk-btf/codegen"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "tkbtf-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	pkg := flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file, defaults to $GOPACKAGE")
+	output := flag.String("o", "", "path of the generated file, defaults to stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: tkbtf-gen [-pkg name] [-o path] [variant=]kprobe_events...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *pkg == "" || flag.NArg() == 0 {
+		flag.Usage()
+		return fmt.Errorf("missing package name or kprobe_events files")
+	}
+
+	generator := codegen.NewGenerator(*pkg)
+	for _, arg := range flag.Args() {
+		variantName, path, ok := strings.Cut(arg, "=")
+		if !ok {
+			path = arg
+			variantName = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		if err := addVariant(generator, variantName, path); err != nil {
+			return err
+		}
+	}
+
+	var source bytes.Buffer
+	if err := generator.Generate(&source); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(source.Bytes())
+		return err
+	}
+
+	return os.WriteFile(*output, source.Bytes(), 0o644)
+}
+
+// addVariant adds the events of the given kprobe_events file to the generator as the given variant.
+func addVariant(generator *codegen.Generator, variantName string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	events, err := tkbtf.ParseKprobeEvents(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return generator.AddKprobeEvents(variantName, events...)
+}
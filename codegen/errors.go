@@ -0,0 +1,31 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package codegen
+
+import "errors"
+
+var (
+	// ErrUnsupportedFetchArgType means that there is no Go type for the type of a fetch arg, e.g. string[2].
+	ErrUnsupportedFetchArgType = errors.New("unsupported fetch arg type")
+	// ErrMissingEventName means that a kprobe event doesn't define the name that its records can be matched with.
+	ErrMissingEventName = errors.New("missing event name")
+	// ErrDuplicateVariant means that a variant was added more than once.
+	ErrDuplicateVariant = errors.New("duplicate variant")
+	// ErrNoEvents means that there are no events to generate code for.
+	ErrNoEvents = errors.New("no events")
+)
@@ -0,0 +1,445 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package codegen generates, from the probes of Symbols, Go types that mirror the records of their trace events
+// along with decoders that decode the records without reflection.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	tkbtf "github.com/elastic/tk-btf"
+)
+
+// arrayTypeRegex matches the array fetch arg types, e.g. u32[4].
+var arrayTypeRegex = regexp.MustCompile(`^(.+)\[(\d+)\]$`)
+
+// bitfieldTypeRegex matches the bitfield fetch arg types, e.g. b4@2/32, capturing the size of the container.
+var bitfieldTypeRegex = regexp.MustCompile(`^b\d+@\d+/(\d+)$`)
+
+// intTypeRegex matches the integer fetch arg types, e.g. u32, s8 and x64.
+var intTypeRegex = regexp.MustCompile(`^([usx])(8|16|32|64)$`)
+
+// Generator generates Go source with a struct type per trace event and a decoder of its records. Since the types
+// of the fetch args may be inferred differently per kernel, e.g. with FetchArgTypeAuto, the events are added per
+// variant, namely per kernel that the probes were built against. When the fields of an event are the same across
+// all variants a single type is generated, otherwise one per distinct set of fields, suffixed with the name of the
+// first variant that defines it.
+type Generator struct {
+	pkg      string
+	variants []*variant
+}
+
+// variant holds the events of a variant in the order they were added.
+type variant struct {
+	name   string
+	events []*event
+}
+
+// event holds the name of a trace event and the Go fields that mirror its fetch args.
+type event struct {
+	name   string
+	fields []*field
+}
+
+// field is a Go struct field that mirrors a fetch arg of a trace event.
+type field struct {
+	// Name is the name of the Go struct field.
+	Name string
+	// FetchArg is the name of the fetch arg, namely the name of the field in the trace event format.
+	FetchArg string
+	// GoType is the Go type of the struct field, e.g. uint32 or [4]int16.
+	GoType string
+	// ElemType is the Go type of the elements of array fields, e.g. int16.
+	ElemType string
+	// Accessor is the decoder.Field method that the value, or for arrays every element, is read with.
+	Accessor string
+}
+
+// NewGenerator returns a new Generator of Go source for the given package name.
+func NewGenerator(pkg string) *Generator {
+	return &Generator{pkg: pkg}
+}
+
+// AddSymbols adds, as the given variant, the events of the probes of the given Symbols, which should be already
+// built, e.g. with Spec.BuildSymbols. The events are named after the ID of the probes, see Probe.GetID.
+func (g *Generator) AddSymbols(variantName string, symbols ...*tkbtf.Symbol) error {
+	var events []*tkbtf.KprobeEvent
+	for _, symbol := range symbols {
+		for _, probe := range symbol.GetProbes() {
+			kprobeEvent, err := tkbtf.NewKprobeEvent("", probe)
+			if err != nil {
+				return fmt.Errorf("probe %s: %w", probe.GetID(), err)
+			}
+			events = append(events, kprobeEvent)
+		}
+	}
+
+	return g.AddKprobeEvents(variantName, events...)
+}
+
+// AddKprobeEvents adds, as the given variant, the given kprobe events, e.g. as parsed from a kprobe_events file
+// with ParseKprobeEvents. Every event must have a name.
+func (g *Generator) AddKprobeEvents(variantName string, kprobeEvents ...*tkbtf.KprobeEvent) error {
+	for _, v := range g.variants {
+		if v.name == variantName {
+			return fmt.Errorf("variant %s: %w", variantName, ErrDuplicateVariant)
+		}
+	}
+
+	v := &variant{name: variantName}
+	for _, kprobeEvent := range kprobeEvents {
+		if kprobeEvent.Event == "" {
+			return fmt.Errorf("kprobe event of symbol %s: %w", kprobeEvent.Symbol, ErrMissingEventName)
+		}
+
+		e, err := newEvent(kprobeEvent)
+		if err != nil {
+			return fmt.Errorf("variant %s: %w", variantName, err)
+		}
+		v.events = append(v.events, e)
+	}
+
+	g.variants = append(g.variants, v)
+	return nil
+}
+
+// newEvent returns the event with the Go fields that mirror the fetch args of the given kprobe event.
+func newEvent(kprobeEvent *tkbtf.KprobeEvent) (*event, error) {
+	e := &event{name: kprobeEvent.Event}
+
+	names := make(map[string]struct{}, len(kprobeEvent.FetchArgs))
+	for i, fetchArg := range kprobeEvent.FetchArgs {
+		fetchArgName := fetchArg.Name
+		if fetchArgName == "" {
+			// the tracing fs names the unnamed fetch args after their position
+			fetchArgName = fmt.Sprintf("arg%d", i+1)
+		}
+
+		f, err := newField(fetchArgName, fetchArg.Type)
+		if err != nil {
+			return nil, fmt.Errorf("event %s: %w", kprobeEvent.Event, err)
+		}
+
+		// distinct fetch args, e.g. a_b and aB, may map to the same Go identifier
+		name := f.Name
+		for j := 2; ; j++ {
+			if _, ok := names[name]; !ok {
+				break
+			}
+			name = f.Name + strconv.Itoa(j)
+		}
+		f.Name = name
+		names[name] = struct{}{}
+
+		e.fields = append(e.fields, f)
+	}
+
+	return e, nil
+}
+
+// newField returns the Go field that mirrors the fetch arg with the given name and type.
+func newField(fetchArgName string, fetchArgType string) (*field, error) {
+	f := &field{
+		Name:     exportedIdentifier(fetchArgName),
+		FetchArg: fetchArgName,
+	}
+
+	if arrayMatches := arrayTypeRegex.FindStringSubmatch(fetchArgType); arrayMatches != nil {
+		if arrayMatches[1] == "char" {
+			// char arrays are decoded as strings
+			f.GoType, f.Accessor = "string", "String"
+			return f, nil
+		}
+
+		elemType, accessor, err := goScalarType(arrayMatches[1])
+		if err != nil || accessor == "String" {
+			return nil, fmt.Errorf("fetch arg %s of type %s: %w", fetchArgName, fetchArgType, ErrUnsupportedFetchArgType)
+		}
+
+		f.GoType = "[" + arrayMatches[2] + "]" + elemType
+		f.ElemType = elemType
+		f.Accessor = accessor + "At"
+		return f, nil
+	}
+
+	goType, accessor, err := goScalarType(fetchArgType)
+	if err != nil {
+		return nil, fmt.Errorf("fetch arg %s of type %s: %w", fetchArgName, fetchArgType, err)
+	}
+
+	f.GoType, f.Accessor = goType, accessor
+	return f, nil
+}
+
+// goScalarType returns the Go type of the given non-array fetch arg type and the decoder.Field method that reads
+// it.
+func goScalarType(fetchArgType string) (string, string, error) {
+	if intMatches := intTypeRegex.FindStringSubmatch(fetchArgType); intMatches != nil {
+		if intMatches[1] == "s" {
+			return "int" + intMatches[2], "Int", nil
+		}
+		return "uint" + intMatches[2], "Uint", nil
+	}
+
+	if bitfieldMatches := bitfieldTypeRegex.FindStringSubmatch(fetchArgType); bitfieldMatches != nil {
+		// the bits are extracted into an unsigned integer of the size of their container
+		return "uint" + bitfieldMatches[1], "Uint", nil
+	}
+
+	switch fetchArgType {
+	case "":
+		// the tracing fs fetches the fetch args without a type as x64
+		return "uint64", "Uint", nil
+	case "string", "ustring", "symstr":
+		return "string", "String", nil
+	case "symbol":
+		return "uint64", "Uint", nil
+	case "char":
+		return "uint8", "Uint", nil
+	default:
+		return "", "", ErrUnsupportedFetchArgType
+	}
+}
+
+// exportedIdentifier returns the exported Go identifier of the given name, e.g. FlagsHex for flags_hex.
+func exportedIdentifier(name string) string {
+	var identifier strings.Builder
+
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if identifier.Len() == 0 && unicode.IsDigit(r) {
+			identifier.WriteString("F")
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		identifier.WriteRune(r)
+	}
+
+	if identifier.Len() == 0 {
+		return "F"
+	}
+
+	return identifier.String()
+}
+
+// generatedType is a struct type generated for the fields of an event that are the same across the given variants.
+type generatedType struct {
+	// Name is the name of the struct type.
+	Name string
+	// Event is the name of the trace event.
+	Event string
+	// Variants are the names of the variants that the type is generated for, empty if it is the same across all.
+	Variants []string
+	// Fields are the fields of the struct type.
+	Fields []*field
+}
+
+// VariantsList returns the names of the variants of the type separated by commas.
+func (t *generatedType) VariantsList() string {
+	return strings.Join(t.Variants, ", ")
+}
+
+// types returns the struct types to generate, in the order that the events were added.
+func (g *Generator) types() []*generatedType {
+	var eventNames []string
+	fieldsPerVariant := make(map[string][]*fieldsVariants)
+	for _, v := range g.variants {
+		for _, e := range v.events {
+			groups, ok := fieldsPerVariant[e.name]
+			if !ok {
+				eventNames = append(eventNames, e.name)
+			}
+
+			found := false
+			for _, group := range groups {
+				if sameFields(group.fields, e.fields) {
+					group.variants = append(group.variants, v.name)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				groups = append(groups, &fieldsVariants{fields: e.fields, variants: []string{v.name}})
+			}
+			fieldsPerVariant[e.name] = groups
+		}
+	}
+
+	var types []*generatedType
+	for _, eventName := range eventNames {
+		baseName := exportedIdentifier(eventName) + "Event"
+
+		groups := fieldsPerVariant[eventName]
+		if len(groups) == 1 {
+			types = append(types, &generatedType{Name: baseName, Event: eventName, Fields: groups[0].fields})
+			continue
+		}
+
+		for _, group := range groups {
+			types = append(types, &generatedType{
+				Name:     baseName + exportedIdentifier(group.variants[0]),
+				Event:    eventName,
+				Variants: group.variants,
+				Fields:   group.fields,
+			})
+		}
+	}
+
+	return types
+}
+
+// fieldsVariants holds the fields of an event along with the variants that define them.
+type fieldsVariants struct {
+	fields   []*field
+	variants []string
+}
+
+// sameFields returns true if the given fields have the same names and Go types.
+func sameFields(a []*field, b []*field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if *a[i] != *b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Generate writes to the given writer the gofmt-ed Go source of the struct types and the decoders of the added
+// events. If no events were added, an ErrNoEvents error is returned.
+func (g *Generator) Generate(w io.Writer) error {
+	types := g.types()
+	if len(types) == 0 {
+		return ErrNoEvents
+	}
+
+	// fmt is used only to report the fields that are missing from the formats
+	needsFmt := false
+	for _, t := range types {
+		needsFmt = needsFmt || len(t.Fields) > 0
+	}
+
+	var source bytes.Buffer
+	if err := sourceTemplate.Execute(&source, struct {
+		Package  string
+		Types    []*generatedType
+		NeedsFmt bool
+	}{
+		Package:  g.pkg,
+		Types:    types,
+		NeedsFmt: needsFmt,
+	}); err != nil {
+		return err
+	}
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(formatted)
+	return err
+}
+
+// sourceTemplate is the template of the generated Go source.
+var sourceTemplate = template.Must(template.New("source").Parse(`// Code generated by tkbtf-gen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+{{- if .NeedsFmt }}
+	"fmt"
+{{ end }}
+	"github.com/elastic/tk-btf/decoder"
+)
+{{ range .Types }}{{ $type := . }}
+// {{ .Name }} holds the fields of a record of the {{ .Event }} trace event
+{{- if .Variants }} for the variants {{ .VariantsList }}{{ end }}.
+type {{ .Name }} struct {
+{{- range .Fields }}
+	{{ .Name }} {{ .GoType }} ` + "`tkbtf:\"{{ .FetchArg }}\"`" + `
+{{- end }}
+}
+
+// {{ .Name }}Decoder decodes the records of the {{ .Event }} trace event into {{ .Name }} without reflection.
+type {{ .Name }}Decoder struct {
+{{- range .Fields }}
+	field{{ .Name }} *decoder.Field
+{{- end }}
+}
+
+// New{{ .Name }}Decoder returns a {{ .Name }}Decoder for the given format of the {{ .Event }} trace event.
+// If the format lacks any of the fields, a decoder.ErrFieldNotFound error is returned.
+func New{{ .Name }}Decoder(format *decoder.Format) (*{{ .Name }}Decoder, error) {
+	d := &{{ .Name }}Decoder{}
+{{- if .Fields }}
+	var ok bool
+{{- end }}
+{{- range .Fields }}
+	if d.field{{ .Name }}, ok = format.Field("{{ .FetchArg }}"); !ok {
+		return nil, fmt.Errorf("{{ $type.Event }} field {{ .FetchArg }}: %w", decoder.ErrFieldNotFound)
+	}
+{{- end }}
+	return d, nil
+}
+
+// Decode decodes the given raw record of the {{ .Event }} trace event into the given {{ .Name }}.
+func (d *{{ .Name }}Decoder) Decode(record []byte, event *{{ .Name }}) error {
+{{- range .Fields }}
+{{- if .ElemType }}
+	for i := range event.{{ .Name }} {
+		v, err := d.field{{ .Name }}.{{ .Accessor }}(record, i)
+		if err != nil {
+			return err
+		}
+		event.{{ .Name }}[i] = {{ .ElemType }}(v)
+	}
+{{- else }}
+	{
+		v, err := d.field{{ .Name }}.{{ .Accessor }}(record)
+		if err != nil {
+			return err
+		}
+		event.{{ .Name }} = {{ if eq .GoType "string" }}v{{ else }}{{ .GoType }}(v){{ end }}
+	}
+{{- end }}
+{{- end }}
+	return nil
+}
+{{ end }}`))
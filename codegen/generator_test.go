@@ -0,0 +1,190 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package codegen
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	tkbtf "github.com/elastic/tk-btf"
+)
+
+func TestGenerator_UpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "testevents")
+
+	generator := NewGenerator("testevents")
+	for _, variantName := range []string{"linux-5.10", "linux-6.1"} {
+		f, err := os.Open(filepath.Join(dir, variantName+".kprobe_events"))
+		require.NoError(t, err)
+
+		events, err := tkbtf.ParseKprobeEvents(f)
+		require.NoError(t, f.Close())
+		require.NoError(t, err)
+
+		require.NoError(t, generator.AddKprobeEvents(variantName, events...))
+	}
+
+	var source bytes.Buffer
+	require.NoError(t, generator.Generate(&source))
+
+	generated, err := os.ReadFile(filepath.Join(dir, "events_gen.go"))
+	require.NoError(t, err)
+	require.Equal(t, string(generated), source.String(), "run go generate ./codegen/...")
+}
+
+// buildSymbol builds against a minimal BTF spec, in which the param of test_function has the given integer type,
+// a symbol with a kprobe that infers the type of its fetch arg.
+func buildSymbol(t *testing.T, paramType *btf.Int) *tkbtf.Symbol {
+	t.Helper()
+
+	testFunction := &btf.Func{
+		Name: "test_function",
+		Type: &btf.FuncProto{
+			Return: &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed},
+			Params: []btf.FuncParam{{Name: "count", Type: paramType}},
+		},
+		Linkage: btf.GlobalFunc,
+	}
+
+	builder, err := btf.NewBuilder([]btf.Type{testFunction})
+	require.NoError(t, err)
+	raw, err := builder.Marshal(nil, nil)
+	require.NoError(t, err)
+
+	spec, err := tkbtf.NewSpecFromReader(bytes.NewReader(raw), nil)
+	if errors.Is(err, tkbtf.ErrUnsupportedArch) {
+		t.Skip("the architecture is not supported")
+	}
+	require.NoError(t, err)
+
+	symbol := tkbtf.NewSymbol("test_function").AddProbes(
+		tkbtf.NewKProbe().AddFetchArgs(
+			tkbtf.NewFetchArg("count", tkbtf.FetchArgTypeAuto).FuncParamWithName("count"),
+			tkbtf.NewFetchArg("comm", "string").Comm(),
+		),
+	)
+	require.NoError(t, spec.BuildSymbol(symbol))
+
+	return symbol
+}
+
+func TestGenerator_AddSymbols(t *testing.T) {
+	intType := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	longType := &btf.Int{Name: "long", Size: 8, Encoding: btf.Signed}
+
+	same := NewGenerator("events")
+	require.NoError(t, same.AddSymbols("a", buildSymbol(t, intType)))
+	require.NoError(t, same.AddSymbols("b", buildSymbol(t, intType)))
+	require.ErrorIs(t, same.AddSymbols("b", buildSymbol(t, intType)), ErrDuplicateVariant)
+
+	var source bytes.Buffer
+	require.NoError(t, same.Generate(&source))
+	require.Contains(t, source.String(), "type KprobeTestFunctionEvent struct {\n\tCount int32  `tkbtf:\"count\"`\n\tComm  string `tkbtf:\"comm\"`\n}")
+	require.Contains(t, source.String(), "func NewKprobeTestFunctionEventDecoder(format *decoder.Format)")
+
+	differ := NewGenerator("events")
+	require.NoError(t, differ.AddSymbols("a", buildSymbol(t, intType)))
+	require.NoError(t, differ.AddSymbols("b", buildSymbol(t, longType)))
+	require.NoError(t, differ.AddSymbols("c", buildSymbol(t, intType)))
+
+	source.Reset()
+	require.NoError(t, differ.Generate(&source))
+	require.NotContains(t, source.String(), "type KprobeTestFunctionEvent struct")
+	require.Contains(t, source.String(), "trace event for the variants a, c.\ntype KprobeTestFunctionEventA struct {\n\tCount int32 ")
+	require.Contains(t, source.String(), "trace event for the variants b.\ntype KprobeTestFunctionEventB struct {\n\tCount int64 ")
+	require.NotContains(t, source.String(), "KprobeTestFunctionEventC")
+}
+
+func TestGenerator_Errors(t *testing.T) {
+	require.ErrorIs(t, NewGenerator("events").Generate(&bytes.Buffer{}), ErrNoEvents)
+
+	unnamed, err := tkbtf.ParseKprobeEvent("p test_function arg=%di:u32")
+	require.NoError(t, err)
+	require.ErrorIs(t, NewGenerator("events").AddKprobeEvents("a", unnamed), ErrMissingEventName)
+
+	unsupported, err := tkbtf.ParseKprobeEvent("p:test_function test_function arg=+0(%di):string[2]")
+	require.NoError(t, err)
+	require.ErrorIs(t, NewGenerator("events").AddKprobeEvents("a", unsupported), ErrUnsupportedFetchArgType)
+
+	noFetchArgs, err := tkbtf.ParseKprobeEvent("r:test_function_ret test_function")
+	require.NoError(t, err)
+
+	generator := NewGenerator("events")
+	require.NoError(t, generator.AddKprobeEvents("a", noFetchArgs))
+
+	var source bytes.Buffer
+	require.NoError(t, generator.Generate(&source))
+	require.NotContains(t, source.String(), `"fmt"`)
+	require.NotContains(t, source.String(), "var ok bool")
+}
+
+func TestNewField(t *testing.T) {
+	tcs := []struct {
+		fetchArgName string
+		fetchArgType string
+		expected     field
+		err          error
+	}{
+		{fetchArgName: "fi", fetchArgType: "u32", expected: field{Name: "Fi", FetchArg: "fi", GoType: "uint32", Accessor: "Uint"}},
+		{fetchArgName: "cnt", fetchArgType: "s16", expected: field{Name: "Cnt", FetchArg: "cnt", GoType: "int16", Accessor: "Int"}},
+		{fetchArgName: "flags_hex", fetchArgType: "x8", expected: field{Name: "FlagsHex", FetchArg: "flags_hex", GoType: "uint8", Accessor: "Uint"}},
+		{fetchArgName: "ptr", fetchArgType: "", expected: field{Name: "Ptr", FetchArg: "ptr", GoType: "uint64", Accessor: "Uint"}},
+		{fetchArgName: "mode", fetchArgType: "b4@2/16", expected: field{Name: "Mode", FetchArg: "mode", GoType: "uint16", Accessor: "Uint"}},
+		{fetchArgName: "fn", fetchArgType: "ustring", expected: field{Name: "Fn", FetchArg: "fn", GoType: "string", Accessor: "String"}},
+		{fetchArgName: "sym", fetchArgType: "symbol", expected: field{Name: "Sym", FetchArg: "sym", GoType: "uint64", Accessor: "Uint"}},
+		{fetchArgName: "c", fetchArgType: "char", expected: field{Name: "C", FetchArg: "c", GoType: "uint8", Accessor: "Uint"}},
+		{fetchArgName: "name", fetchArgType: "char[16]", expected: field{Name: "Name", FetchArg: "name", GoType: "string", Accessor: "String"}},
+		{fetchArgName: "arr", fetchArgType: "s64[2]", expected: field{Name: "Arr", FetchArg: "arr", GoType: "[2]int64", ElemType: "int64", Accessor: "IntAt"}},
+		{fetchArgName: "1st", fetchArgType: "u8", expected: field{Name: "F1st", FetchArg: "1st", GoType: "uint8", Accessor: "Uint"}},
+		{fetchArgName: "strs", fetchArgType: "string[2]", err: ErrUnsupportedFetchArgType},
+		{fetchArgName: "f", fetchArgType: "f32", err: ErrUnsupportedFetchArgType},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.fetchArgName+":"+tc.fetchArgType, func(t *testing.T) {
+			f, err := newField(tc.fetchArgName, tc.fetchArgType)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, *f)
+		})
+	}
+}
+
+func TestNewEvent_DuplicateFieldNames(t *testing.T) {
+	kprobeEvent, err := tkbtf.ParseKprobeEvent("p:test test_function a_b=%di:u32 aB=%si:u32 %dx:u8")
+	require.NoError(t, err)
+
+	e, err := newEvent(kprobeEvent)
+	require.NoError(t, err)
+
+	var names []string
+	for _, f := range e.fields {
+		names = append(names, f.Name+"="+f.FetchArg)
+	}
+	require.Equal(t, "AB=a_b AB2=aB Arg3=arg3", strings.Join(names, " "))
+}
@@ -0,0 +1,22 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package testevents holds the code generated by tkbtf-gen for the test kprobe events of two kernel variants, in
+// which the cnt fetch arg of the kprobe_test_function event differs.
+package testevents
+
+//go:generate go run github.com/elastic/tk-btf/cmd/tkbtf-gen -o events_gen.go linux-5.10.kprobe_events linux-6.1.kprobe_events
@@ -0,0 +1,228 @@
+// Code generated by tkbtf-gen. DO NOT EDIT.
+
+package testevents
+
+import (
+	"fmt"
+
+	"github.com/elastic/tk-btf/decoder"
+)
+
+// KprobeTestFunctionEventLinux510 holds the fields of a record of the kprobe_test_function trace event for the variants linux-5.10.
+type KprobeTestFunctionEventLinux510 struct {
+	Fi   uint32    `tkbtf:"fi"`
+	Fn   string    `tkbtf:"fn"`
+	Mid  uint8     `tkbtf:"mid"`
+	Cnt  int64     `tkbtf:"cnt"`
+	Arr  [4]uint32 `tkbtf:"arr"`
+	Comm string    `tkbtf:"comm"`
+}
+
+// KprobeTestFunctionEventLinux510Decoder decodes the records of the kprobe_test_function trace event into KprobeTestFunctionEventLinux510 without reflection.
+type KprobeTestFunctionEventLinux510Decoder struct {
+	fieldFi   *decoder.Field
+	fieldFn   *decoder.Field
+	fieldMid  *decoder.Field
+	fieldCnt  *decoder.Field
+	fieldArr  *decoder.Field
+	fieldComm *decoder.Field
+}
+
+// NewKprobeTestFunctionEventLinux510Decoder returns a KprobeTestFunctionEventLinux510Decoder for the given format of the kprobe_test_function trace event.
+// If the format lacks any of the fields, a decoder.ErrFieldNotFound error is returned.
+func NewKprobeTestFunctionEventLinux510Decoder(format *decoder.Format) (*KprobeTestFunctionEventLinux510Decoder, error) {
+	d := &KprobeTestFunctionEventLinux510Decoder{}
+	var ok bool
+	if d.fieldFi, ok = format.Field("fi"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field fi: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldFn, ok = format.Field("fn"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field fn: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldMid, ok = format.Field("mid"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field mid: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldCnt, ok = format.Field("cnt"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field cnt: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldArr, ok = format.Field("arr"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field arr: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldComm, ok = format.Field("comm"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field comm: %w", decoder.ErrFieldNotFound)
+	}
+	return d, nil
+}
+
+// Decode decodes the given raw record of the kprobe_test_function trace event into the given KprobeTestFunctionEventLinux510.
+func (d *KprobeTestFunctionEventLinux510Decoder) Decode(record []byte, event *KprobeTestFunctionEventLinux510) error {
+	{
+		v, err := d.fieldFi.Uint(record)
+		if err != nil {
+			return err
+		}
+		event.Fi = uint32(v)
+	}
+	{
+		v, err := d.fieldFn.String(record)
+		if err != nil {
+			return err
+		}
+		event.Fn = v
+	}
+	{
+		v, err := d.fieldMid.Uint(record)
+		if err != nil {
+			return err
+		}
+		event.Mid = uint8(v)
+	}
+	{
+		v, err := d.fieldCnt.Int(record)
+		if err != nil {
+			return err
+		}
+		event.Cnt = int64(v)
+	}
+	for i := range event.Arr {
+		v, err := d.fieldArr.UintAt(record, i)
+		if err != nil {
+			return err
+		}
+		event.Arr[i] = uint32(v)
+	}
+	{
+		v, err := d.fieldComm.String(record)
+		if err != nil {
+			return err
+		}
+		event.Comm = v
+	}
+	return nil
+}
+
+// KprobeTestFunctionEventLinux61 holds the fields of a record of the kprobe_test_function trace event for the variants linux-6.1.
+type KprobeTestFunctionEventLinux61 struct {
+	Fi   uint32    `tkbtf:"fi"`
+	Fn   string    `tkbtf:"fn"`
+	Mid  uint8     `tkbtf:"mid"`
+	Cnt  int32     `tkbtf:"cnt"`
+	Arr  [4]uint32 `tkbtf:"arr"`
+	Comm string    `tkbtf:"comm"`
+}
+
+// KprobeTestFunctionEventLinux61Decoder decodes the records of the kprobe_test_function trace event into KprobeTestFunctionEventLinux61 without reflection.
+type KprobeTestFunctionEventLinux61Decoder struct {
+	fieldFi   *decoder.Field
+	fieldFn   *decoder.Field
+	fieldMid  *decoder.Field
+	fieldCnt  *decoder.Field
+	fieldArr  *decoder.Field
+	fieldComm *decoder.Field
+}
+
+// NewKprobeTestFunctionEventLinux61Decoder returns a KprobeTestFunctionEventLinux61Decoder for the given format of the kprobe_test_function trace event.
+// If the format lacks any of the fields, a decoder.ErrFieldNotFound error is returned.
+func NewKprobeTestFunctionEventLinux61Decoder(format *decoder.Format) (*KprobeTestFunctionEventLinux61Decoder, error) {
+	d := &KprobeTestFunctionEventLinux61Decoder{}
+	var ok bool
+	if d.fieldFi, ok = format.Field("fi"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field fi: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldFn, ok = format.Field("fn"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field fn: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldMid, ok = format.Field("mid"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field mid: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldCnt, ok = format.Field("cnt"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field cnt: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldArr, ok = format.Field("arr"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field arr: %w", decoder.ErrFieldNotFound)
+	}
+	if d.fieldComm, ok = format.Field("comm"); !ok {
+		return nil, fmt.Errorf("kprobe_test_function field comm: %w", decoder.ErrFieldNotFound)
+	}
+	return d, nil
+}
+
+// Decode decodes the given raw record of the kprobe_test_function trace event into the given KprobeTestFunctionEventLinux61.
+func (d *KprobeTestFunctionEventLinux61Decoder) Decode(record []byte, event *KprobeTestFunctionEventLinux61) error {
+	{
+		v, err := d.fieldFi.Uint(record)
+		if err != nil {
+			return err
+		}
+		event.Fi = uint32(v)
+	}
+	{
+		v, err := d.fieldFn.String(record)
+		if err != nil {
+			return err
+		}
+		event.Fn = v
+	}
+	{
+		v, err := d.fieldMid.Uint(record)
+		if err != nil {
+			return err
+		}
+		event.Mid = uint8(v)
+	}
+	{
+		v, err := d.fieldCnt.Int(record)
+		if err != nil {
+			return err
+		}
+		event.Cnt = int32(v)
+	}
+	for i := range event.Arr {
+		v, err := d.fieldArr.UintAt(record, i)
+		if err != nil {
+			return err
+		}
+		event.Arr[i] = uint32(v)
+	}
+	{
+		v, err := d.fieldComm.String(record)
+		if err != nil {
+			return err
+		}
+		event.Comm = v
+	}
+	return nil
+}
+
+// KretprobeTestFunctionEvent holds the fields of a record of the kretprobe_test_function trace event.
+type KretprobeTestFunctionEvent struct {
+	Ret int32 `tkbtf:"ret"`
+}
+
+// KretprobeTestFunctionEventDecoder decodes the records of the kretprobe_test_function trace event into KretprobeTestFunctionEvent without reflection.
+type KretprobeTestFunctionEventDecoder struct {
+	fieldRet *decoder.Field
+}
+
+// NewKretprobeTestFunctionEventDecoder returns a KretprobeTestFunctionEventDecoder for the given format of the kretprobe_test_function trace event.
+// If the format lacks any of the fields, a decoder.ErrFieldNotFound error is returned.
+func NewKretprobeTestFunctionEventDecoder(format *decoder.Format) (*KretprobeTestFunctionEventDecoder, error) {
+	d := &KretprobeTestFunctionEventDecoder{}
+	var ok bool
+	if d.fieldRet, ok = format.Field("ret"); !ok {
+		return nil, fmt.Errorf("kretprobe_test_function field ret: %w", decoder.ErrFieldNotFound)
+	}
+	return d, nil
+}
+
+// Decode decodes the given raw record of the kretprobe_test_function trace event into the given KretprobeTestFunctionEvent.
+func (d *KretprobeTestFunctionEventDecoder) Decode(record []byte, event *KretprobeTestFunctionEvent) error {
+	{
+		v, err := d.fieldRet.Int(record)
+		if err != nil {
+			return err
+		}
+		event.Ret = int32(v)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testevents
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/tk-btf/decoder"
+)
+
+func TestKprobeTestFunctionEventLinux61Decoder(t *testing.T) {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("the record fixtures are little-endian")
+	}
+
+	fixture := filepath.Join("..", "..", "..", "decoder", "testdata", "kprobe_test_function")
+
+	formatFile, err := os.Open(fixture + ".format")
+	require.NoError(t, err)
+	defer formatFile.Close()
+
+	format, err := decoder.ParseFormat(formatFile)
+	require.NoError(t, err)
+
+	record, err := os.ReadFile(fixture + ".record")
+	require.NoError(t, err)
+
+	d, err := NewKprobeTestFunctionEventLinux61Decoder(format)
+	require.NoError(t, err)
+
+	var event KprobeTestFunctionEventLinux61
+	require.NoError(t, d.Decode(record, &event))
+	require.Equal(t, KprobeTestFunctionEventLinux61{
+		Fi:   1337,
+		Fn:   "hello.txt",
+		Mid:  1,
+		Cnt:  -5,
+		Arr:  [4]uint32{1, 2, 3, 4},
+		Comm: "bash",
+	}, event)
+
+	// the generated types keep the tkbtf tags, thus they decode the same with reflection
+	var reflected KprobeTestFunctionEventLinux61
+	require.NoError(t, format.DecodeInto(record, &reflected))
+	require.Equal(t, event, reflected)
+
+	// the format of the kprobe event lacks the fields of the kretprobe one
+	_, err = NewKretprobeTestFunctionEventDecoder(format)
+	require.ErrorIs(t, err, decoder.ErrFieldNotFound)
+
+	// the fields are read with the size of the format, thus the wider cnt of the linux-5.10 variant holds the value
+	d510, err := NewKprobeTestFunctionEventLinux510Decoder(format)
+	require.NoError(t, err)
+
+	var event510 KprobeTestFunctionEventLinux510
+	require.NoError(t, d510.Decode(record, &event510))
+	require.Equal(t, int64(-5), event510.Cnt)
+}
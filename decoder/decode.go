@@ -0,0 +1,297 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structTagName is the name of the struct tag that maps a struct field to a field of the trace event format,
+// e.g. `tkbtf:"fi"`.
+const structTagName = "tkbtf"
+
+// Decode decodes the given raw record, which starts with the common fields of the trace event, into a map keyed by
+// field name. Integers are decoded as int64 or uint64 depending on their sign, arrays as []int64 or []uint64, char
+// arrays and dynamic char fields, e.g. __data_loc char[], as strings, and any other dynamic field as []byte.
+// Records are decoded with the byte order of the host, namely the one the kernel writes them with.
+func (f *Format) Decode(record []byte) (map[string]any, error) {
+	values := make(map[string]any, len(f.Fields))
+
+	for i := range f.Fields {
+		value, err := decodeField(record, &f.Fields[i])
+		if err != nil {
+			return nil, err
+		}
+
+		values[f.Fields[i].Name] = value
+	}
+
+	return values, nil
+}
+
+// DecodeInto decodes the given raw record into the struct that dst points to. Every struct field with a tkbtf tag,
+// e.g. `tkbtf:"fi"`, gets the value of the trace event field with the same name, as decoded by Decode. Integers
+// can be assigned to any integer type that holds the value, strings to string fields, and arrays to slices or arrays
+// of integers. A tag that doesn't match any field of the format results in an ErrFieldNotFound error and a value
+// that can't be assigned in an ErrFieldTypeMismatch error.
+func (f *Format) DecodeInto(record []byte, dst any) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Pointer || dstValue.IsNil() || dstValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T: %w", dst, ErrInvalidDestination)
+	}
+
+	structValue := dstValue.Elem()
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		structField := structType.Field(i)
+
+		name, ok := structField.Tag.Lookup(structTagName)
+		if !ok || name == "" || name == "-" || !structField.IsExported() {
+			continue
+		}
+
+		field, ok := f.Field(name)
+		if !ok {
+			return fmt.Errorf("struct field %s tagged as %s: %w", structField.Name, name, ErrFieldNotFound)
+		}
+
+		value, err := decodeField(record, field)
+		if err != nil {
+			return err
+		}
+
+		if err := assignValue(structValue.Field(i), value); err != nil {
+			return fmt.Errorf("struct field %s tagged as %s: %w", structField.Name, name, err)
+		}
+	}
+
+	return nil
+}
+
+// decodeField decodes the given field of the raw record.
+func decodeField(record []byte, field *Field) (any, error) {
+	data, err := fieldBytes(record, field.Offset, field.Size)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", field.Name, err)
+	}
+
+	switch {
+	case field.DataLoc:
+		if field.Size != 4 {
+			return nil, fmt.Errorf("dynamic field %s of size %d: %w", field.Name, field.Size, ErrUnsupportedField)
+		}
+
+		// the lower 16 bits hold the offset of the data and the upper ones their size
+		dataLoc := binary.NativeEndian.Uint32(data)
+		dataOffset := int(dataLoc & 0xffff)
+		dataSize := int(dataLoc >> 16)
+		if field.RelLoc {
+			dataOffset += field.Offset + field.Size
+		}
+
+		dynamicData, err := fieldBytes(record, dataOffset, dataSize)
+		if err != nil {
+			return nil, fmt.Errorf("dynamic field %s: %w", field.Name, err)
+		}
+
+		if isCharType(field.Type) {
+			return cString(dynamicData), nil
+		}
+
+		return bytes.Clone(dynamicData), nil
+	case field.ArrayLen > 0:
+		if isCharType(field.Type) {
+			return cString(data), nil
+		}
+
+		elemSize := field.Size / field.ArrayLen
+		if elemSize*field.ArrayLen != field.Size {
+			return nil, fmt.Errorf("array field %s of size %d: %w", field.Name, field.Size, ErrUnsupportedField)
+		}
+
+		if field.Signed {
+			elems := make([]int64, field.ArrayLen)
+			for i := range elems {
+				elem, err := decodeInt(data[i*elemSize:(i+1)*elemSize], true)
+				if err != nil {
+					return nil, fmt.Errorf("array field %s: %w", field.Name, err)
+				}
+				elems[i] = elem.(int64)
+			}
+			return elems, nil
+		}
+
+		elems := make([]uint64, field.ArrayLen)
+		for i := range elems {
+			elem, err := decodeInt(data[i*elemSize:(i+1)*elemSize], false)
+			if err != nil {
+				return nil, fmt.Errorf("array field %s: %w", field.Name, err)
+			}
+			elems[i] = elem.(uint64)
+		}
+		return elems, nil
+	default:
+		value, err := decodeInt(data, field.Signed)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		return value, nil
+	}
+}
+
+// fieldBytes returns the bytes of the record at the given offset and size.
+func fieldBytes(record []byte, offset int, size int) ([]byte, error) {
+	if offset < 0 || size < 0 || offset+size > len(record) {
+		return nil, fmt.Errorf("%d bytes at offset %d of %d bytes: %w", size, offset, len(record), ErrRecordTooShort)
+	}
+
+	return record[offset : offset+size], nil
+}
+
+// decodeInt decodes the given bytes as an int64, if signed is true, or an uint64.
+func decodeInt(data []byte, signed bool) (any, error) {
+	var value uint64
+	var signedValue int64
+
+	switch len(data) {
+	case 1:
+		value = uint64(data[0])
+		signedValue = int64(int8(data[0]))
+	case 2:
+		v := binary.NativeEndian.Uint16(data)
+		value = uint64(v)
+		signedValue = int64(int16(v))
+	case 4:
+		v := binary.NativeEndian.Uint32(data)
+		value = uint64(v)
+		signedValue = int64(int32(v))
+	case 8:
+		value = binary.NativeEndian.Uint64(data)
+		signedValue = int64(value)
+	default:
+		return nil, fmt.Errorf("integer of size %d: %w", len(data), ErrUnsupportedField)
+	}
+
+	if signed {
+		return signedValue, nil
+	}
+
+	return value, nil
+}
+
+// isCharType returns true if the given field type holds characters, e.g. char or __data_loc char[].
+func isCharType(fieldType string) bool {
+	fieldType = strings.TrimPrefix(fieldType, "__data_loc ")
+	fieldType = strings.TrimPrefix(fieldType, "__rel_loc ")
+	fieldType = strings.TrimPrefix(fieldType, "const ")
+	fieldType = strings.TrimSuffix(fieldType, "[]")
+	return strings.TrimSpace(fieldType) == "char"
+}
+
+// cString returns the string of the given bytes up to the first NUL character.
+func cString(data []byte) string {
+	if end := bytes.IndexByte(data, 0); end >= 0 {
+		data = data[:end]
+	}
+
+	return string(data)
+}
+
+// assignValue assigns the given decoded value to the given struct field.
+func assignValue(dst reflect.Value, value any) error {
+	if dst.Kind() == reflect.Interface {
+		dst.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch v := value.(type) {
+	case int64:
+		return assignInt(dst, v, v < 0, uint64(v))
+	case uint64:
+		return assignInt(dst, int64(v), false, v)
+	case string:
+		if dst.Kind() != reflect.String {
+			break
+		}
+		dst.SetString(v)
+		return nil
+	case []byte:
+		if dst.Kind() != reflect.Slice || dst.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		dst.SetBytes(v)
+		return nil
+	case []int64:
+		return assignElems(dst, len(v), func(i int, elem reflect.Value) error {
+			return assignInt(elem, v[i], v[i] < 0, uint64(v[i]))
+		})
+	case []uint64:
+		return assignElems(dst, len(v), func(i int, elem reflect.Value) error {
+			return assignInt(elem, int64(v[i]), false, v[i])
+		})
+	}
+
+	return fmt.Errorf("%T to %s: %w", value, dst.Type(), ErrFieldTypeMismatch)
+}
+
+// assignInt assigns the given integer, as signed and unsigned value, to the given integer struct field.
+func assignInt(dst reflect.Value, signedValue int64, negative bool, unsignedValue uint64) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if (!negative && unsignedValue > 1<<63-1) || dst.OverflowInt(signedValue) {
+			break
+		}
+		dst.SetInt(signedValue)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if negative || dst.OverflowUint(unsignedValue) {
+			break
+		}
+		dst.SetUint(unsignedValue)
+		return nil
+	}
+
+	return fmt.Errorf("integer %d to %s: %w", signedValue, dst.Type(), ErrFieldTypeMismatch)
+}
+
+// assignElems assigns the given number of elements to the given slice or array struct field, using assignElem
+// for every element.
+func assignElems(dst reflect.Value, length int, assignElem func(i int, elem reflect.Value) error) error {
+	switch dst.Kind() {
+	case reflect.Slice:
+		dst.Set(reflect.MakeSlice(dst.Type(), length, length))
+	case reflect.Array:
+		if dst.Len() != length {
+			return fmt.Errorf("%d elements to %s: %w", length, dst.Type(), ErrFieldTypeMismatch)
+		}
+	default:
+		return fmt.Errorf("%d elements to %s: %w", length, dst.Type(), ErrFieldTypeMismatch)
+	}
+
+	for i := 0; i < length; i++ {
+		if err := assignElem(i, dst.Index(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// loadFixture returns the parsed format and the raw record of the trace event fixture with the given name. The
+// fixtures prefixed with a kernel version, e.g. linux-6.18_, were captured from the tracing fs of that kernel on
+// amd64, the uprobe ones by probing test_function of a small C program. That kernel doesn't support kprobe events,
+// thus the bitfield uprobe one stands in for a kprobe, since both share the record layout and the fetch arg types;
+// it was defined with fi=%di:u32 fn=+0(%si):string mid=+0(%dx):b4@3/32 cnt=%cx:s32 arr=+0(%r8):u32[4] comm=$comm.
+// The rest are synthetic, written by hand to cover the fixed size arrays of older kernels. All of them are
+// little-endian, thus the tests are skipped on big-endian hosts.
+func loadFixture(t *testing.T, name string) (*Format, []byte) {
+	t.Helper()
+
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("the record fixtures are little-endian")
+	}
+
+	formatFile, err := os.Open(filepath.Join("testdata", name+".format"))
+	require.NoError(t, err)
+	defer formatFile.Close()
+
+	format, err := ParseFormat(formatFile)
+	require.NoError(t, err)
+
+	record, err := os.ReadFile(filepath.Join("testdata", name+".record"))
+	require.NoError(t, err)
+
+	return format, record
+}
+
+func TestParseFormat(t *testing.T) {
+	format, _ := loadFixture(t, "kprobe_test_function")
+
+	require.Equal(t, "kprobe_test_function", format.Name)
+	require.Equal(t, uint32(1234), format.ID)
+	require.Len(t, format.Fields, 11)
+
+	commonPid, ok := format.Field("common_pid")
+	require.True(t, ok)
+	require.True(t, commonPid.IsCommon())
+	require.Equal(t, Field{Name: "common_pid", Type: "int", Offset: 4, Size: 4, Signed: true}, *commonPid)
+
+	fn, ok := format.Field("fn")
+	require.True(t, ok)
+	require.False(t, fn.IsCommon())
+	require.Equal(t, Field{Name: "fn", Type: "__data_loc char[]", Offset: 20, Size: 4, Signed: true, DataLoc: true}, *fn)
+
+	arr, ok := format.Field("arr")
+	require.True(t, ok)
+	require.Equal(t, Field{Name: "arr", Type: "u32", Offset: 32, Size: 16, ArrayLen: 4}, *arr)
+
+	format, _ = loadFixture(t, "linux-6.18_uprobe_test_function")
+
+	arr, ok = format.Field("arr")
+	require.True(t, ok)
+	require.Equal(t, Field{Name: "arr", Type: "u32", Offset: 24, Size: 16, ArrayLen: 4}, *arr)
+
+	_, err := ParseFormat(strings.NewReader("name: unsized\nID: 1\nformat:\n\tfield:pid_t arr[];\toffset:8;\tsize:8;\tsigned:1;\n"))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = ParseFormat(strings.NewReader("name: invalid\nID: 1\nformat:\n\tfield:u32 fi;\toffset:a;\tsize:4;\tsigned:0;\n"))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	_, err = ParseFormat(strings.NewReader("name: empty\nID: 1\nformat:\n"))
+	require.ErrorIs(t, err, ErrInvalidFormat)
+}
+
+func TestFormat_Decode(t *testing.T) {
+	tcs := []struct {
+		name     string
+		expected map[string]any
+	}{
+		{
+			name: "kprobe_test_function",
+			expected: map[string]any{
+				"common_type":          uint64(1234),
+				"common_flags":         uint64(1),
+				"common_preempt_count": uint64(0),
+				"common_pid":           int64(4242),
+				"__probe_ip":           uint64(0xffffffff81234567),
+				"fi":                   uint64(1337),
+				"fn":                   "hello.txt",
+				"mid":                  uint64(1),
+				"cnt":                  int64(-5),
+				"arr":                  []uint64{1, 2, 3, 4},
+				"comm":                 "bash",
+			},
+		},
+		{
+			name: "sched_process_fork",
+			expected: map[string]any{
+				"common_type":          uint64(315),
+				"common_flags":         uint64(0),
+				"common_preempt_count": uint64(1),
+				"common_pid":           int64(1000),
+				"parent_comm":          "bash",
+				"parent_pid":           int64(1000),
+				"child_comm":           "sleep",
+				"child_pid":            int64(1001),
+			},
+		},
+		{
+			name: "linux-6.18_sched_process_fork",
+			expected: map[string]any{
+				"common_type":          uint64(366),
+				"common_flags":         uint64(0),
+				"common_preempt_count": uint64(0),
+				"common_pid":           int64(26581),
+				"parent_comm":          "bash",
+				"parent_pid":           int64(26581),
+				"child_comm":           "bash",
+				"child_pid":            int64(26596),
+			},
+		},
+		{
+			name: "linux-6.18_uprobe_test_function",
+			expected: map[string]any{
+				"common_type":          uint64(2226),
+				"common_flags":         uint64(0xff),
+				"common_preempt_count": uint64(0xff),
+				"common_pid":           int64(26597),
+				"__probe_ip":           uint64(0x560a407ce129),
+				"fn":                   "hello.txt",
+				"cnt":                  int64(-5),
+				"arr":                  []uint64{1, 2, 3, 4},
+				"comm":                 "tp",
+			},
+		},
+		{
+			name: "linux-6.18_uprobe_test_function_bitfield",
+			expected: map[string]any{
+				"common_type":          uint64(2226),
+				"common_flags":         uint64(0xff),
+				"common_preempt_count": uint64(0xff),
+				"common_pid":           int64(12809),
+				"__probe_ip":           uint64(0x5642b58e5129),
+				"fi":                   uint64(1337),
+				"fn":                   "hello.txt",
+				"mid":                  uint64(10),
+				"cnt":                  int64(-5),
+				"arr":                  []uint64{1, 2, 3, 4},
+				"comm":                 "bf",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			format, record := loadFixture(t, tc.name)
+
+			values, err := format.Decode(record)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, values)
+
+			_, err = format.Decode(record[:20])
+			require.ErrorIs(t, err, ErrRecordTooShort)
+		})
+	}
+}
+
+func TestFormat_DecodeInto(t *testing.T) {
+	format, record := loadFixture(t, "linux-6.18_uprobe_test_function_bitfield")
+
+	type event struct {
+		Pid      int32     `tkbtf:"common_pid"`
+		IP       uintptr   `tkbtf:"__probe_ip"`
+		Ino      uint32    `tkbtf:"fi"`
+		Name     string    `tkbtf:"fn"`
+		IsDir    bool      `tkbtf:"-"`
+		Count    int       `tkbtf:"cnt"`
+		Numbers  [4]uint16 `tkbtf:"arr"`
+		Comm     string    `tkbtf:"comm"`
+		Any      any       `tkbtf:"mid"`
+		Untagged string
+	}
+
+	var decoded event
+	require.NoError(t, format.DecodeInto(record, &decoded))
+	require.Equal(t, event{
+		Pid:     12809,
+		IP:      0x5642b58e5129,
+		Ino:     1337,
+		Name:    "hello.txt",
+		Count:   -5,
+		Numbers: [4]uint16{1, 2, 3, 4},
+		Comm:    "bf",
+		Any:     uint64(10),
+	}, decoded)
+
+	var unsignedCount struct {
+		Count uint32 `tkbtf:"cnt"`
+	}
+	require.ErrorIs(t, format.DecodeInto(record, &unsignedCount), ErrFieldTypeMismatch)
+
+	var narrowIP struct {
+		IP uint16 `tkbtf:"__probe_ip"`
+	}
+	require.ErrorIs(t, format.DecodeInto(record, &narrowIP), ErrFieldTypeMismatch)
+
+	var unknown struct {
+		Unknown uint32 `tkbtf:"unknown"`
+	}
+	require.ErrorIs(t, format.DecodeInto(record, &unknown), ErrFieldNotFound)
+
+	require.ErrorIs(t, format.DecodeInto(record, decoded), ErrInvalidDestination)
+}
+
+func TestField_Accessors(t *testing.T) {
+	format, record := loadFixture(t, "kprobe_test_function")
+
+	field := func(name string) *Field {
+		f, ok := format.Field(name)
+		require.True(t, ok)
+		return f
+	}
+
+	fi, err := field("fi").Uint(record)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1337), fi)
+
+	cnt, err := field("cnt").Int(record)
+	require.NoError(t, err)
+	require.Equal(t, int64(-5), cnt)
+
+	arr, err := field("arr").UintAt(record, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), arr)
+
+	signedArr, err := field("arr").IntAt(record, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), signedArr)
+
+	fn, err := field("fn").String(record)
+	require.NoError(t, err)
+	require.Equal(t, "hello.txt", fn)
+
+	_, err = field("arr").UintAt(record, 4)
+	require.ErrorIs(t, err, ErrFieldTypeMismatch)
+
+	_, err = field("arr").Uint(record)
+	require.ErrorIs(t, err, ErrFieldTypeMismatch)
+
+	_, err = field("fi").UintAt(record, 0)
+	require.ErrorIs(t, err, ErrFieldTypeMismatch)
+
+	_, err = field("fn").Int(record)
+	require.ErrorIs(t, err, ErrFieldTypeMismatch)
+
+	_, err = field("fi").String(record)
+	require.ErrorIs(t, err, ErrFieldTypeMismatch)
+
+	_, err = field("fi").Uint(record[:10])
+	require.ErrorIs(t, err, ErrRecordTooShort)
+}
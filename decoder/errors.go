@@ -0,0 +1,37 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import "errors"
+
+var (
+	// ErrInvalidFormat means that the trace event format could not be parsed.
+	ErrInvalidFormat = errors.New("invalid trace event format")
+	// ErrRecordTooShort means that a field of the trace event format lies outside the raw record.
+	ErrRecordTooShort = errors.New("record too short")
+	// ErrUnsupportedField means that the size of a field of the trace event format is not supported.
+	ErrUnsupportedField = errors.New("unsupported field")
+	// ErrFieldNotFound means that the struct tag of a struct field doesn't match any field of the trace event format.
+	ErrFieldNotFound = errors.New("field not found in trace event format")
+	// ErrFieldTypeMismatch means that the decoded value of a field can't be assigned to the respective struct field.
+	ErrFieldTypeMismatch = errors.New("field type mismatch")
+	// ErrInvalidTraceLine means that a trace_pipe line of a known event could not be parsed.
+	ErrInvalidTraceLine = errors.New("invalid trace_pipe line")
+	// ErrInvalidDestination means that the destination of DecodeInto is not a non-nil pointer to a struct.
+	ErrInvalidDestination = errors.New("invalid decode destination")
+)
@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import "fmt"
+
+// Uint returns the value of the integer field in the given raw record as an uint64. It doesn't reflect, thus it is
+// meant for generated decoders; any field that isn't a plain integer results in an ErrFieldTypeMismatch error.
+func (f *Field) Uint(record []byte) (uint64, error) {
+	if f.DataLoc || f.ArrayLen > 0 {
+		return 0, fmt.Errorf("field %s of type %s to integer: %w", f.Name, f.Type, ErrFieldTypeMismatch)
+	}
+
+	value, err := f.integer(record, f.Offset, f.Size, false)
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// Int returns the value of the integer field in the given raw record as an int64, sign extended from the size of
+// the field. Any field that isn't a plain integer results in an ErrFieldTypeMismatch error.
+func (f *Field) Int(record []byte) (int64, error) {
+	if f.DataLoc || f.ArrayLen > 0 {
+		return 0, fmt.Errorf("field %s of type %s to integer: %w", f.Name, f.Type, ErrFieldTypeMismatch)
+	}
+
+	value, err := f.integer(record, f.Offset, f.Size, true)
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// UintAt returns the element at the given index of the integer array field in the given raw record as an uint64.
+// Any field that isn't an integer array results in an ErrFieldTypeMismatch error.
+func (f *Field) UintAt(record []byte, index int) (uint64, error) {
+	offset, size, err := f.elem(index)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := f.integer(record, offset, size, false)
+	if err != nil {
+		return 0, err
+	}
+	return value.(uint64), nil
+}
+
+// IntAt returns the element at the given index of the integer array field in the given raw record as an int64,
+// sign extended from the size of the element. Any field that isn't an integer array results in an
+// ErrFieldTypeMismatch error.
+func (f *Field) IntAt(record []byte, index int) (int64, error) {
+	offset, size, err := f.elem(index)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := f.integer(record, offset, size, true)
+	if err != nil {
+		return 0, err
+	}
+	return value.(int64), nil
+}
+
+// String returns the value of the char array field or the dynamic char field, e.g. __data_loc char[], in the given
+// raw record. Any other field results in an ErrFieldTypeMismatch error.
+func (f *Field) String(record []byte) (string, error) {
+	if !isCharType(f.Type) || (!f.DataLoc && f.ArrayLen == 0) {
+		return "", fmt.Errorf("field %s of type %s to string: %w", f.Name, f.Type, ErrFieldTypeMismatch)
+	}
+
+	value, err := decodeField(record, f)
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// integer decodes the integer of the given size at the given offset of the raw record, as int64 if signed is true,
+// otherwise as uint64.
+func (f *Field) integer(record []byte, offset int, size int, signed bool) (any, error) {
+	data, err := fieldBytes(record, offset, size)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", f.Name, err)
+	}
+
+	value, err := decodeInt(data, signed)
+	if err != nil {
+		return nil, fmt.Errorf("field %s: %w", f.Name, err)
+	}
+	return value, nil
+}
+
+// elem returns the offset and the size of the element at the given index of the array field.
+func (f *Field) elem(index int) (int, int, error) {
+	if f.DataLoc || f.ArrayLen == 0 {
+		return 0, 0, fmt.Errorf("field %s of type %s to array: %w", f.Name, f.Type, ErrFieldTypeMismatch)
+	}
+
+	if index < 0 || index >= f.ArrayLen {
+		return 0, 0, fmt.Errorf("index %d of array field %s of length %d: %w", index, f.Name, f.ArrayLen,
+			ErrFieldTypeMismatch)
+	}
+
+	elemSize := f.Size / f.ArrayLen
+	if elemSize*f.ArrayLen != f.Size {
+		return 0, 0, fmt.Errorf("array field %s of size %d: %w", f.Name, f.Size, ErrUnsupportedField)
+	}
+
+	return f.Offset + index*elemSize, elemSize, nil
+}
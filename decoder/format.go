@@ -0,0 +1,231 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package decoder parses the format of trace events, as found in the events/<group>/<event>/format file of the
+// tracing fs, and decodes the raw records of the perf and ftrace ring buffers of these events.
+package decoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// commonFieldPrefix is the name prefix of the fields that all trace events share, e.g. common_pid.
+const commonFieldPrefix = "common_"
+
+// Field describes a field of a trace event format, e.g.
+// field:u32 fi;	offset:16;	size:4;	signed:0;
+type Field struct {
+	// Name is the name of the field, namely the name of the fetch arg for kprobe events.
+	Name string
+	// Type is the C type of the field, e.g. u32 or __data_loc char[]. Note that bitfield fetch args are exposed with
+	// the type of their container, e.g. u32, and the record holds the already extracted bits.
+	Type string
+	// Offset is the offset in bytes of the field in the raw record.
+	Offset int
+	// Size is the size in bytes of the field in the raw record.
+	Size int
+	// Signed is true when the field holds a signed integer.
+	Signed bool
+	// ArrayLen is the number of elements of an array field, e.g. 4 for u32 arr[4], otherwise zero.
+	ArrayLen int
+	// DataLoc is true for dynamic fields, e.g. strings, whose data reside after the fixed fields of the record.
+	// The field holds the offset of the data in the lower 16 bits and their size in the upper 16 bits.
+	DataLoc bool
+	// RelLoc is true when the offset of a dynamic field is relative to the end of the field, instead of the start of
+	// the record.
+	RelLoc bool
+}
+
+// IsCommon returns true if the field is shared by all trace events, e.g. common_pid.
+func (f *Field) IsCommon() bool {
+	return strings.HasPrefix(f.Name, commonFieldPrefix)
+}
+
+// Format is the parsed representation of a trace event format.
+type Format struct {
+	// Name is the name of the trace event, e.g. kprobe_do_sys_open.
+	Name string
+	// ID is the ID of the trace event, namely the value of the common_type field of its records.
+	ID uint32
+	// Fields holds the fields of the trace event, starting with the common ones, in the order they are defined.
+	Fields []Field
+}
+
+// ParseFormat parses the trace event format of the given reader. When the format is malformed,
+// an ErrInvalidFormat error is returned.
+func ParseFormat(rd io.Reader) (*Format, error) {
+	format := &Format{}
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "name:"):
+			format.Name = strings.TrimSpace(strings.TrimPrefix(line, "name:"))
+		case strings.HasPrefix(line, "ID:"):
+			id, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "ID:")), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ID in %q: %w", line, ErrInvalidFormat)
+			}
+			format.ID = uint32(id)
+		case strings.HasPrefix(line, "field:"):
+			field, err := parseField(line)
+			if err != nil {
+				return nil, err
+			}
+			format.Fields = append(format.Fields, field)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(format.Fields) == 0 {
+		return nil, fmt.Errorf("missing fields: %w", ErrInvalidFormat)
+	}
+
+	return format, nil
+}
+
+// Field returns the field of the trace event format with the given name.
+func (f *Format) Field(name string) (*Field, bool) {
+	for i := range f.Fields {
+		if f.Fields[i].Name == name {
+			return &f.Fields[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// parseField parses a field line of a trace event format, e.g.
+// field:__data_loc char[] fn;	offset:20;	size:4;	signed:1;
+func parseField(line string) (Field, error) {
+	var field Field
+	var unsizedArray bool
+
+	for _, attribute := range strings.Split(line, ";") {
+		attribute = strings.TrimSpace(attribute)
+		if attribute == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(attribute, ":")
+		if !ok {
+			return Field{}, fmt.Errorf("invalid attribute %q in %q: %w", attribute, line, ErrInvalidFormat)
+		}
+
+		var err error
+		switch key {
+		case "field":
+			unsizedArray, err = parseFieldDeclaration(&field, value)
+		case "offset":
+			field.Offset, err = strconv.Atoi(value)
+		case "size":
+			field.Size, err = strconv.Atoi(value)
+		case "signed":
+			field.Signed = value == "1"
+		}
+
+		if err != nil {
+			return Field{}, fmt.Errorf("invalid attribute %q in %q: %w", attribute, line, ErrInvalidFormat)
+		}
+	}
+
+	if field.Name == "" || field.Size <= 0 || field.Offset < 0 {
+		return Field{}, fmt.Errorf("incomplete field %q: %w", line, ErrInvalidFormat)
+	}
+
+	if unsizedArray {
+		// the length of arrays declared without one, e.g. u32 arr[] of the array fetch args of probe events,
+		// is derived from the size of the field
+		size := elemSize(field.Type)
+		if size == 0 || field.Size%size != 0 {
+			return Field{}, fmt.Errorf("array field of unknown length %q: %w", line, ErrInvalidFormat)
+		}
+		field.ArrayLen = field.Size / size
+	}
+
+	return field, nil
+}
+
+// parseFieldDeclaration parses the C declaration of a field, e.g. u32 arr[4] or __data_loc char[] fn. It returns
+// true if the field is an array declared without a length, e.g. u32 arr[].
+func parseFieldDeclaration(field *Field, declaration string) (bool, error) {
+	declaration = strings.TrimSpace(declaration)
+
+	nameStart := strings.LastIndexAny(declaration, " \t*")
+	if nameStart < 0 {
+		return false, ErrInvalidFormat
+	}
+
+	field.Type = strings.TrimSpace(declaration[:nameStart+1])
+	field.Name = declaration[nameStart+1:]
+
+	unsizedArray := false
+	if name, arrayLen, isArray := strings.Cut(field.Name, "["); isArray {
+		field.Name = name
+
+		arrayLen = strings.TrimSuffix(arrayLen, "]")
+		if arrayLen == "" {
+			unsizedArray = true
+		} else {
+			length, err := strconv.Atoi(arrayLen)
+			if err != nil || length <= 0 {
+				return false, ErrInvalidFormat
+			}
+			field.ArrayLen = length
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(field.Type, "__data_loc "):
+		field.DataLoc = true
+	case strings.HasPrefix(field.Type, "__rel_loc "):
+		field.DataLoc = true
+		field.RelLoc = true
+	}
+
+	if field.Type == "" || field.Name == "" {
+		return false, ErrInvalidFormat
+	}
+
+	return unsizedArray, nil
+}
+
+// elemSize returns the size in bytes of the given element type of an array field, e.g. 4 for u32, or zero if it is
+// unknown.
+func elemSize(elemType string) int {
+	switch elemType {
+	case "u8", "s8", "char", "signed char", "unsigned char":
+		return 1
+	case "u16", "s16", "short", "unsigned short":
+		return 2
+	case "u32", "s32", "int", "unsigned int":
+		return 4
+	case "u64", "s64", "long long", "unsigned long long":
+		return 8
+	default:
+		return 0
+	}
+}
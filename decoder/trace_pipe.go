@@ -0,0 +1,327 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tkbtf "github.com/elastic/tk-btf"
+)
+
+// tracePipeLineRegex matches the lines of the trace_pipe file, e.g.
+// bash-1234    [002] d..3.  1234.567890: kprobe_do_sys_open: (do_sys_open+0x0/0x200) dfd=0xffffff9c
+// The task name may contain spaces and dashes, the tgid and the flags columns are optional.
+var tracePipeLineRegex = regexp.MustCompile(
+	`^\s*(.+)-(\d+)\s+(?:\(\s*[\d-]+\)\s+)?\[(\d+)\]\s+(?:(\S+)\s+)?(\d+)\.(\d+):\s+(\S+):\s+\(([^)]*)\)\s?(.*)$`)
+
+// arrayTypeRegex matches the array fetch arg types, e.g. u32[4].
+var arrayTypeRegex = regexp.MustCompile(`^(.+)\[(\d+)\]$`)
+
+// TraceEvent is an event line of the trace_pipe file that belongs to one of the probes of a TracePipeParser.
+type TraceEvent struct {
+	// Task is the name of the task that triggered the event.
+	Task string
+	// PID is the id of the thread that triggered the event.
+	PID int
+	// CPU is the cpu that the event occurred on.
+	CPU int
+	// Flags are the irq, preemption and lock depth flags of the event, e.g. d..3., empty if not reported.
+	Flags string
+	// Timestamp is the timestamp of the event.
+	Timestamp time.Duration
+	// Probe is the Probe that the event belongs to.
+	Probe *tkbtf.Probe
+	// ProbePoint is the probed location, e.g. do_sys_open+0x0/0x200, or for kretprobes the return address and the
+	// function, e.g. ksys_open+0x6e/0x90 <- do_sys_open.
+	ProbePoint string
+	// Values holds the values of the fetch args keyed by name, converted according to their type, see
+	// TracePipeParser.
+	Values map[string]any
+}
+
+// TracePipeParser parses the event lines of the trace_pipe file of the tracing fs that belong to the given probes.
+type TracePipeParser struct {
+	scanner *bufio.Scanner
+	probes  map[string]*tracePipeProbe
+}
+
+// tracePipeProbe holds a Probe along with its parsed fetch args.
+type tracePipeProbe struct {
+	probe     *tkbtf.Probe
+	fetchArgs []*tkbtf.KprobeFetchArg
+}
+
+// NewTracePipeParser returns a TracePipeParser that streams over the given reader and recognizes the events of the
+// given built probes by their ID, see Probe.GetID, which is expected to be the name they were registered with.
+func NewTracePipeParser(rd io.Reader, probes ...*tkbtf.Probe) (*TracePipeParser, error) {
+	parser := &TracePipeParser{
+		scanner: bufio.NewScanner(rd),
+		probes:  make(map[string]*tracePipeProbe, len(probes)),
+	}
+
+	for _, probe := range probes {
+		parsedProbe := &tracePipeProbe{probe: probe}
+		for i, token := range strings.Fields(probe.GetTracingEventProbe()) {
+			fetchArg, err := tkbtf.ParseKprobeFetchArg(token)
+			if err != nil {
+				return nil, fmt.Errorf("probe %s: %w", probe.GetID(), err)
+			}
+
+			if fetchArg.Name == "" {
+				// the tracing fs names the unnamed fetch args after their position
+				fetchArg.Name = fmt.Sprintf("arg%d", i+1)
+			}
+
+			parsedProbe.fetchArgs = append(parsedProbe.fetchArgs, fetchArg)
+		}
+
+		parser.probes[probe.GetID()] = parsedProbe
+	}
+
+	return parser, nil
+}
+
+// Next returns the next event line that belongs to one of the probes of the TracePipeParser, skipping any other
+// lines. The fetch arg values are converted according to their types: unsigned and bitfield types to uint64, hex
+// types to uint64, signed types to int64, string, ustring, symstr and symbol types to string, char to string, arrays
+// to []uint64, []int64 or []any, and values that the kernel failed to fetch, namely (fault), to nil. At the end of
+// the reader it returns io.EOF. When an event line can't be parsed, an ErrInvalidTraceLine error is returned and
+// Next can be called again to continue with the next line.
+func (p *TracePipeParser) Next() (*TraceEvent, error) {
+	for p.scanner.Scan() {
+		line := p.scanner.Text()
+
+		matches := tracePipeLineRegex.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		probe, ok := p.probes[matches[7]]
+		if !ok {
+			continue
+		}
+
+		return probe.parseEvent(line, matches)
+	}
+
+	if err := p.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+// parseEvent parses the event line of the probe based on the submatches of tracePipeLineRegex.
+func (p *tracePipeProbe) parseEvent(line string, matches []string) (*TraceEvent, error) {
+	pid, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return nil, fmt.Errorf("pid of %q: %w", line, ErrInvalidTraceLine)
+	}
+
+	cpu, err := strconv.Atoi(matches[3])
+	if err != nil {
+		return nil, fmt.Errorf("cpu of %q: %w", line, ErrInvalidTraceLine)
+	}
+
+	timestamp, err := parseTimestamp(matches[5], matches[6])
+	if err != nil {
+		return nil, fmt.Errorf("timestamp of %q: %w", line, ErrInvalidTraceLine)
+	}
+
+	event := &TraceEvent{
+		Task:       strings.TrimSpace(matches[1]),
+		PID:        pid,
+		CPU:        cpu,
+		Flags:      matches[4],
+		Timestamp:  timestamp,
+		Probe:      p.probe,
+		ProbePoint: matches[8],
+		Values:     make(map[string]any, len(p.fetchArgs)),
+	}
+
+	// the values are split by the names of the fetch args that follow them, since values, e.g. strings, may
+	// contain spaces
+	rest := matches[9]
+	for i, fetchArg := range p.fetchArgs {
+		prefix := fetchArg.Name + "="
+		if !strings.HasPrefix(rest, prefix) {
+			return nil, fmt.Errorf("missing fetch arg %s in %q: %w", fetchArg.Name, line, ErrInvalidTraceLine)
+		}
+		rest = rest[len(prefix):]
+
+		rawValue := rest
+		if i+1 < len(p.fetchArgs) {
+			var end int
+			if isQuotedType(fetchArg.Type) && strings.HasPrefix(rest, `"`) {
+				// search after the closing quote, so that the string may contain the name of the next fetch arg
+				if end = strings.Index(rest, `" `+p.fetchArgs[i+1].Name+"="); end >= 0 {
+					end++
+				}
+			} else {
+				end = strings.Index(rest, " "+p.fetchArgs[i+1].Name+"=")
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("missing fetch arg %s in %q: %w", p.fetchArgs[i+1].Name, line, ErrInvalidTraceLine)
+			}
+
+			rawValue = rest[:end]
+			rest = rest[end+1:]
+		}
+
+		value, err := convertTraceValue(fetchArg.Type, rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("fetch arg %s in %q: %w", fetchArg.Name, line, err)
+		}
+
+		event.Values[fetchArg.Name] = value
+	}
+
+	return event, nil
+}
+
+// parseTimestamp parses the seconds and the fractional part of a trace_pipe timestamp, e.g. 1234 and 567890.
+func parseTimestamp(seconds string, fraction string) (time.Duration, error) {
+	secs, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(fraction) > 9 {
+		fraction = fraction[:9]
+	}
+
+	nanos, err := strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(secs)*time.Second + time.Duration(nanos), nil
+}
+
+// isQuotedType returns true if the trace_pipe prints the values of the given fetch arg type in double quotes.
+func isQuotedType(argType string) bool {
+	return argType == "string" || argType == "ustring" || argType == "symstr"
+}
+
+// convertTraceValue converts the given trace_pipe value according to the given fetch arg type.
+func convertTraceValue(argType string, rawValue string) (any, error) {
+	if rawValue == "(fault)" {
+		return nil, nil
+	}
+
+	if arrayMatches := arrayTypeRegex.FindStringSubmatch(argType); arrayMatches != nil {
+		return convertTraceArray(arrayMatches[1], rawValue)
+	}
+
+	switch {
+	case argType == "":
+		// the tracing fs fetches the fetch args without a type as x64
+		return convertTraceValue("x64", rawValue)
+	case isQuotedType(argType):
+		if len(rawValue) < 2 || !strings.HasPrefix(rawValue, `"`) || !strings.HasSuffix(rawValue, `"`) {
+			return nil, fmt.Errorf("string %s: %w", rawValue, ErrInvalidTraceLine)
+		}
+		return rawValue[1 : len(rawValue)-1], nil
+	case argType == "symbol":
+		return rawValue, nil
+	case argType == "char":
+		return strings.TrimSuffix(strings.TrimPrefix(rawValue, "'"), "'"), nil
+	case strings.HasPrefix(argType, "x"):
+		value, err := strconv.ParseUint(strings.TrimPrefix(rawValue, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("hex %s: %w", rawValue, ErrInvalidTraceLine)
+		}
+		return value, nil
+	case strings.HasPrefix(argType, "s"):
+		value, err := strconv.ParseInt(rawValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("signed %s: %w", rawValue, ErrInvalidTraceLine)
+		}
+		return value, nil
+	case strings.HasPrefix(argType, "u"), strings.HasPrefix(argType, "b"):
+		// bitfields are printed as the unsigned value of the extracted bits
+		value, err := strconv.ParseUint(rawValue, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unsigned %s: %w", rawValue, ErrInvalidTraceLine)
+		}
+		return value, nil
+	default:
+		return rawValue, nil
+	}
+}
+
+// convertTraceArray converts the given trace_pipe array value, e.g. {1,2,3,4}, according to the given element type.
+func convertTraceArray(elemType string, rawValue string) (any, error) {
+	if !strings.HasPrefix(rawValue, "{") || !strings.HasSuffix(rawValue, "}") {
+		return nil, fmt.Errorf("array %s: %w", rawValue, ErrInvalidTraceLine)
+	}
+
+	rawElems := strings.Split(rawValue[1:len(rawValue)-1], ",")
+	elems := make([]any, len(rawElems))
+	for i, rawElem := range rawElems {
+		elem, err := convertTraceValue(elemType, rawElem)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = elem
+	}
+
+	switch elemKind(elems) {
+	case "uint64":
+		return typedElems[uint64](elems), nil
+	case "int64":
+		return typedElems[int64](elems), nil
+	default:
+		return elems, nil
+	}
+}
+
+// elemKind returns the common type of the given converted elements, ignoring the ones that the kernel failed to
+// fetch, or an empty string if there isn't any.
+func elemKind(elems []any) string {
+	var kind string
+	for _, elem := range elems {
+		if elem == nil {
+			continue
+		}
+
+		elemKind := fmt.Sprintf("%T", elem)
+		if kind != "" && kind != elemKind {
+			return ""
+		}
+		kind = elemKind
+	}
+	return kind
+}
+
+// typedElems returns the given elements as a slice of T. Elements that the kernel failed to fetch are zero.
+func typedElems[T any](elems []any) []T {
+	typed := make([]T, len(elems))
+	for i, elem := range elems {
+		if v, ok := elem.(T); ok {
+			typed[i] = v
+		}
+	}
+	return typed
+}
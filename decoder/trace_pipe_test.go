@@ -0,0 +1,202 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package decoder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	tkbtf "github.com/elastic/tk-btf"
+)
+
+// buildTracePipeProbes builds, against a minimal BTF spec of test_function(dentry_param *dentry), a kprobe with
+// fetch args of various types and a kretprobe with the return value.
+func buildTracePipeProbes(t *testing.T) (*tkbtf.Probe, *tkbtf.Probe) {
+	t.Helper()
+
+	u32 := &btf.Int{Name: "unsigned int", Size: 4}
+	s32 := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	char := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	dentry := &btf.Struct{
+		Name: "dentry",
+		Size: 48,
+		Members: []btf.Member{
+			{Name: "d_flags", Type: u32, Offset: 0},
+			{Name: "d_counts", Type: &btf.Array{Index: u32, Type: s32, Nelems: 2}, Offset: 32},
+			{Name: "d_iname", Type: &btf.Array{Index: u32, Type: char, Nelems: 32}, Offset: 96},
+		},
+	}
+	testFunction := &btf.Func{
+		Name: "test_function",
+		Type: &btf.FuncProto{
+			Return: s32,
+			Params: []btf.FuncParam{{Name: "dentry_param", Type: &btf.Pointer{Target: dentry}}},
+		},
+		Linkage: btf.GlobalFunc,
+	}
+
+	builder, err := btf.NewBuilder([]btf.Type{testFunction})
+	require.NoError(t, err)
+	raw, err := builder.Marshal(nil, nil)
+	require.NoError(t, err)
+
+	spec, err := tkbtf.NewSpecFromReader(bytes.NewReader(raw), nil)
+	if errors.Is(err, tkbtf.ErrUnsupportedArch) {
+		t.Skip("the architecture is not supported")
+	}
+	require.NoError(t, err)
+
+	kprobe := tkbtf.NewKProbe().SetRef("test_probe").AddFetchArgs(
+		tkbtf.NewFetchArg("flags", "u32").FuncParamWithName("dentry_param", "d_flags"),
+		tkbtf.NewFetchArg("flags_hex", "x32").FuncParamWithName("dentry_param", "d_flags"),
+		tkbtf.NewFetchArg("counts", "s32[2]").FuncParamWithName("dentry_param", "d_counts"),
+		tkbtf.NewFetchArg("name", "string").FuncParamWithName("dentry_param", "d_iname"),
+		tkbtf.NewFetchArg("comm", "string").Comm(),
+	)
+	kretprobe := tkbtf.NewKRetProbe().SetRef("test_retprobe").AddFetchArgs(
+		tkbtf.NewFetchArg("ret", "s32").FuncReturnValue(),
+	)
+
+	require.NoError(t, spec.BuildSymbol(tkbtf.NewSymbol("test_function").AddProbes(kprobe, kretprobe)))
+
+	return kprobe, kretprobe
+}
+
+func TestTracePipeParser(t *testing.T) {
+	kprobe, kretprobe := buildTracePipeProbes(t)
+
+	lines := []string{
+		"CPU:2 [LOST 12 EVENTS]",
+		`           my-cat-1234    [002] d..3.  1234.567890: kprobe_test_probe: (test_function+0x0/0x200) flags=32768 flags_hex=0x8000 counts={-1,2} name="a name= comm=x" comm="my-cat"`,
+		`          <idle>-0       [000] ..s1.  1234.600000: other_event: (other+0x0/0x10) arg1=0x1`,
+		`            bash-42      [001] ....  1235.000001: kretprobe_test_retprobe: (ksys_open+0x6e/0x90 <- test_function) ret=-2`,
+		`            bash-42      [001]  1235.5: kprobe_test_probe: (test_function+0x0/0x200) flags=1 flags_hex=0x1 counts={(fault),3} name=(fault) comm="bash"`,
+	}
+
+	parser, err := NewTracePipeParser(strings.NewReader(strings.Join(lines, "\n")), kprobe, kretprobe)
+	require.NoError(t, err)
+
+	event, err := parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, &TraceEvent{
+		Task:       "my-cat",
+		PID:        1234,
+		CPU:        2,
+		Flags:      "d..3.",
+		Timestamp:  1234*time.Second + 567890*time.Microsecond,
+		Probe:      kprobe,
+		ProbePoint: "test_function+0x0/0x200",
+		Values: map[string]any{
+			"flags":     uint64(32768),
+			"flags_hex": uint64(0x8000),
+			"counts":    []int64{-1, 2},
+			"name":      "a name= comm=x",
+			"comm":      "my-cat",
+		},
+	}, event)
+
+	event, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, kretprobe, event.Probe)
+	require.Equal(t, "ksys_open+0x6e/0x90 <- test_function", event.ProbePoint)
+	require.Equal(t, "....", event.Flags)
+	require.Equal(t, map[string]any{"ret": int64(-2)}, event.Values)
+
+	event, err = parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, "", event.Flags)
+	require.Equal(t, 1235*time.Second+500*time.Millisecond, event.Timestamp)
+	require.Equal(t, map[string]any{
+		"flags":     uint64(1),
+		"flags_hex": uint64(1),
+		"counts":    []int64{0, 3},
+		"name":      nil,
+		"comm":      "bash",
+	}, event.Values)
+
+	_, err = parser.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestTracePipeParser_InvalidLine(t *testing.T) {
+	kprobe, _ := buildTracePipeProbes(t)
+
+	lines := []string{
+		`bash-42 [001] .... 1235.000001: kprobe_test_probe: (test_function+0x0/0x200) flags=abc flags_hex=0x1 counts={1,2} name="n" comm="bash"`,
+		`bash-42 [001] .... 1235.000001: kprobe_test_probe: (test_function+0x0/0x200) flags=1 counts={1,2} name="n" comm="bash"`,
+		`bash-42 [001] .... 1235.000002: kprobe_test_probe: (test_function+0x0/0x200) flags=1 flags_hex=0x1 counts={1,2} name="n" comm="bash"`,
+	}
+
+	parser, err := NewTracePipeParser(strings.NewReader(strings.Join(lines, "\n")), kprobe)
+	require.NoError(t, err)
+
+	_, err = parser.Next()
+	require.ErrorIs(t, err, ErrInvalidTraceLine)
+
+	_, err = parser.Next()
+	require.ErrorIs(t, err, ErrInvalidTraceLine)
+
+	event, err := parser.Next()
+	require.NoError(t, err)
+	require.Equal(t, 1235*time.Second+2*time.Microsecond, event.Timestamp)
+}
+
+func TestConvertTraceValue(t *testing.T) {
+	tcs := []struct {
+		name     string
+		argType  string
+		rawValue string
+		expected any
+		err      error
+	}{
+		{name: "unsigned", argType: "u64", rawValue: "18446744073709551615", expected: uint64(18446744073709551615)},
+		{name: "signed", argType: "s16", rawValue: "-12", expected: int64(-12)},
+		{name: "hex", argType: "x8", rawValue: "0xff", expected: uint64(0xff)},
+		{name: "no type", argType: "", rawValue: "0xffff8880", expected: uint64(0xffff8880)},
+		{name: "bitfield", argType: "b4@2/32", rawValue: "5", expected: uint64(5)},
+		{name: "symbol", argType: "symbol", rawValue: "do_sys_open+0x0/0x200", expected: "do_sys_open+0x0/0x200"},
+		{name: "symstr", argType: "symstr", rawValue: `"do_sys_open+0x0/0x200"`, expected: "do_sys_open+0x0/0x200"},
+		{name: "ustring", argType: "ustring", rawValue: `"/tmp/a b"`, expected: "/tmp/a b"},
+		{name: "char", argType: "char", rawValue: "'c'", expected: "c"},
+		{name: "hex array", argType: "x16[3]", rawValue: "{0x1,0x2,0xa}", expected: []uint64{1, 2, 10}},
+		{name: "symbol array", argType: "symbol[2]", rawValue: "{a+0x0/0x1,b+0x0/0x1}", expected: []any{"a+0x0/0x1", "b+0x0/0x1"}},
+		{name: "fault", argType: "u32", rawValue: "(fault)", expected: nil},
+		{name: "invalid signed", argType: "s32", rawValue: "0x10", err: ErrInvalidTraceLine},
+		{name: "unquoted string", argType: "string", rawValue: "abc", err: ErrInvalidTraceLine},
+		{name: "invalid array", argType: "u8[2]", rawValue: "1,2", err: ErrInvalidTraceLine},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := convertTraceValue(tc.argType, tc.rawValue)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value)
+		})
+	}
+}
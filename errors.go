@@ -48,4 +48,39 @@ var (
 	ErrUnsupportedWrapType = errors.New("unsupported wrap type")
 	// ErrArrayIndexInvalidField means that the field specified as an array index is invalid.
 	ErrArrayIndexInvalidField = errors.New("array index invalid field")
+	// ErrUnsupportedAutoType means that the fetch arg type could not be inferred from the resolved btf type.
+	ErrUnsupportedAutoType = errors.New("unsupported btf type for auto fetch arg type")
+	// ErrUnsupportedBitfield means that a bitfield member does not fit in any container supported by the bitfield
+	// fetch arg type.
+	ErrUnsupportedBitfield = errors.New("unsupported bitfield")
+	// ErrInvalidBitfieldFetchArgType means that a fetch arg that resolves to a bitfield member has an explicit type
+	// that is not a bitfield one, e.g. u32, which would fetch the whole container of the bitfield.
+	ErrInvalidBitfieldFetchArgType = errors.New("invalid fetch arg type for bitfield member")
+	// ErrInvalidArrayFetchArgType means that an array fetch arg type, e.g. u32[4], is malformed or doesn't match
+	// the btf array the fields resolve to.
+	ErrInvalidArrayFetchArgType = errors.New("invalid array fetch arg type")
+	// ErrInvalidExpression means that a field path expression could not be parsed. The respective
+	// ExpressionError holds the column where parsing failed.
+	ErrInvalidExpression = errors.New("invalid expression")
+	// ErrInvalidContainerOf means that a container_of field is malformed or the container member doesn't match
+	// the type of the field path it is applied to.
+	ErrInvalidContainerOf = errors.New("invalid container_of field")
+	// ErrGlobalVariableNotFound means that the global variable was not found in the btf spec.
+	ErrGlobalVariableNotFound = errors.New("global variable not found in btf spec")
+	// ErrUnsupportedGlobalVariable means that the global variable can't be fetched, e.g. it is a per-cpu variable.
+	ErrUnsupportedGlobalVariable = errors.New("unsupported global variable")
+	// ErrInvalidFetchVariable means that a tracing fs fetch variable, e.g. $stackN or an immediate string, is
+	// malformed.
+	ErrInvalidFetchVariable = errors.New("invalid fetch variable")
+	// ErrInvalidKprobeFetchArg means that a kprobe fetch arg, e.g. +8(%di):u64, could not be parsed.
+	ErrInvalidKprobeFetchArg = errors.New("invalid kprobe fetch arg")
+	// ErrInvalidKprobeEvent means that a kprobe event definition, e.g. p:kprobes/myprobe do_sys_open, could not be
+	// parsed or it is not a kprobe or kretprobe event.
+	ErrInvalidKprobeEvent = errors.New("invalid kprobe event")
+	// ErrOffsetNotFound means that the offset of a kprobe fetch arg dereference doesn't match any member of the
+	// respective btf type.
+	ErrOffsetNotFound = errors.New("no member at offset")
+	// ErrUntaggedUserSpace means that a kprobe fetch arg dereferences user-space memory through a pointer that the
+	// btf spec doesn't tag with __user, thus its field path would be fetched from kernel-space memory.
+	ErrUntaggedUserSpace = errors.New("user-space dereference without __user type tag")
 )
@@ -18,7 +18,6 @@
 package main
 
 import (
-	"errors"
 	"flag"
 	"golang.org/x/sys/unix"
 	"io/fs"
@@ -46,11 +45,29 @@ const (
 	devMinor = uint32(0x3FF)
 )
 
+// fileInodeTemplate holds the attributes of the inode of a file relative to a struct inode.
+var fileInodeTemplate = tkbtf.NewFetchArgsTemplate().
+	AddFetchArg("i", "u64", "i_ino").
+	AddFetchArg("m", "u8", "i_mode").
+	AddFetchArg("uid", "u32", "i_uid").
+	AddFetchArg("gid", "u32", "i_gid").
+	AddFetchArg("ats", "u64", "i_atime", "tv_sec").
+	AddFetchArg("atn", "u64", "i_atime", "tv_nsec").
+	AddFetchArg("mts", "u64", "i_mtime", "tv_sec").
+	AddFetchArg("mtn", "u64", "i_mtime", "tv_nsec").
+	AddOptionalFetchArg("cts", "u64", "i_ctime", "tv_sec").
+	AddOptionalFetchArg("ctn", "u64", "i_ctime", "tv_nsec")
+
+// parentInodeTemplate holds the attributes of the inode of a parent directory relative to a struct inode.
+var parentInodeTemplate = tkbtf.NewFetchArgsTemplate().
+	AddFetchArg("i", "u64", "i_ino").
+	AddFetchArg("dmj", tkbtf.BitFieldTypeMask(devMajor), "i_sb", "s_dev").
+	AddFetchArg("dmn", tkbtf.BitFieldTypeMask(devMinor), "i_sb", "s_dev")
+
 func loadFSNotifySymbol(symbolMap map[string]*tkbtf.Symbol) {
 	fsNotifySymbol := tkbtf.NewSymbol("fsnotify").AddProbes(
 		// Kprobe for fsnotify with FSNOTIFY_EVENT_PATH (data_type==1)
 		tkbtf.NewKProbe().SetRef("fsnotify_path").AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_parent", "d_inode", "i_ino"),
 			tkbtf.NewFetchArg("mc", tkbtf.BitFieldTypeMask(fsEventCreate)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("md", tkbtf.BitFieldTypeMask(fsEventDelete)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("ma", tkbtf.BitFieldTypeMask(fsEventAttrib)).FuncParamWithName("mask"),
@@ -58,24 +75,15 @@ func loadFSNotifySymbol(symbolMap map[string]*tkbtf.Symbol) {
 			tkbtf.NewFetchArg("mid", tkbtf.BitFieldTypeMask(fsEventIsDir)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmt", tkbtf.BitFieldTypeMask(fsEventMovedTo)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmf", tkbtf.BitFieldTypeMask(fsEventMovedFrom)).FuncParamWithName("mask"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_ctime", "tv_sec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "i_ctime", "tv_nsec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("dt", "s32").FuncParamWithName("data_type").FuncParamWithName("data_is"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
+			tkbtf.NewFetchArg("dt", "s32").FuncParamWithType("int", 0),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_name", "name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_parent", "d_inode"))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithCustomType("data", tkbtf.WrapPointer, "path", "dentry", "d_inode"))...,
 		),
 		// Kprobe for fsnotify with FSNOTIFY_EVENT_INODE (data_type==2)
 		tkbtf.NewKProbe().SetRef("fsnotify_inode").AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithName("dir", "i_ino").FuncParamWithName("to_tell", "i_ino"),
 			tkbtf.NewFetchArg("mc", tkbtf.BitFieldTypeMask(fsEventCreate)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("md", tkbtf.BitFieldTypeMask(fsEventDelete)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("ma", tkbtf.BitFieldTypeMask(fsEventAttrib)).FuncParamWithName("mask"),
@@ -84,24 +92,15 @@ func loadFSNotifySymbol(symbolMap map[string]*tkbtf.Symbol) {
 			tkbtf.NewFetchArg("mmt", tkbtf.BitFieldTypeMask(fsEventMovedTo)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmf", tkbtf.BitFieldTypeMask(fsEventMovedFrom)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("nptr", "u64").FuncParamWithName("file_name"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_ctime", "tv_sec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "i_ctime", "tv_nsec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("dt", "s32").FuncParamWithName("data_type").FuncParamWithName("data_is"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithName("dir", "i_sb", "s_dev").FuncParamWithName("to_tell", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithName("dir", "i_sb", "s_dev").FuncParamWithName("to_tell", "i_sb", "s_dev"),
+			tkbtf.NewFetchArg("dt", "s32").FuncParamWithType("int", 0),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithName("file_name", "name").FuncParamWithName("file_name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithType("struct inode *", 0))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithCustomType("data", tkbtf.WrapPointer, "inode"))...,
 		),
 		// Kprobe for fsnotify with FSNOTIFY_EVENT_DENTRY (data_type==3)
 		tkbtf.NewKProbe().SetRef("fsnotify_dentry").AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_parent", "d_inode", "i_ino"),
 			tkbtf.NewFetchArg("mc", tkbtf.BitFieldTypeMask(fsEventCreate)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("md", tkbtf.BitFieldTypeMask(fsEventDelete)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("ma", tkbtf.BitFieldTypeMask(fsEventAttrib)).FuncParamWithName("mask"),
@@ -109,20 +108,12 @@ func loadFSNotifySymbol(symbolMap map[string]*tkbtf.Symbol) {
 			tkbtf.NewFetchArg("mid", tkbtf.BitFieldTypeMask(fsEventIsDir)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmt", tkbtf.BitFieldTypeMask(fsEventMovedTo)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmf", tkbtf.BitFieldTypeMask(fsEventMovedFrom)).FuncParamWithName("mask"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_ctime", "tv_sec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "i_ctime", "tv_nsec").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("dt", "s32").FuncParamWithName("data_type").FuncParamWithName("data_is"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
+			tkbtf.NewFetchArg("dt", "s32").FuncParamWithType("int", 0),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_name", "name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_parent", "d_inode"))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithCustomType("data", tkbtf.WrapPointer, "dentry", "d_inode"))...,
 		),
 	)
 
@@ -132,20 +123,11 @@ func loadFSNotifySymbol(symbolMap map[string]*tkbtf.Symbol) {
 func loadVFSGetAttr(symbolMap map[string]*tkbtf.Symbol) {
 	vfsGetAttrSymbol := tkbtf.NewSymbol("vfs_getattr_nosec", "vfs_getattr").AddProbes(
 		tkbtf.NewKProbe().AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithName("path", "dentry", "d_parent", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithName("path", "dentry", "d_inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithName("path", "dentry", "d_inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithName("path", "dentry", "d_inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_ctime", "tv_sec").FuncParamWithName("path", "dentry", "d_inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithName("path", "dentry", "d_inode", "i_ctime", "tv_nsec").FuncParamWithName("path", "dentry", "d_inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithName("path", "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithName("path", "dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithName("path", "dentry", "d_name", "name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithName("path", "dentry", "d_parent", "d_inode"))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithName("path", "dentry", "d_inode"))...,
 		),
 	)
 
@@ -155,7 +137,6 @@ func loadVFSGetAttr(symbolMap map[string]*tkbtf.Symbol) {
 func loadFSNotifyParentSymbol(symbolMap map[string]*tkbtf.Symbol) {
 	fsNotifyParentSymbol := tkbtf.NewSymbol("__fsnotify_parent", "fsnotify_parent").AddProbes(
 		tkbtf.NewKProbe().AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithName("dentry", "d_parent", "d_inode", "i_ino"),
 			tkbtf.NewFetchArg("mc", tkbtf.BitFieldTypeMask(fsEventCreate)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("md", tkbtf.BitFieldTypeMask(fsEventDelete)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("ma", tkbtf.BitFieldTypeMask(fsEventAttrib)).FuncParamWithName("mask"),
@@ -163,19 +144,11 @@ func loadFSNotifyParentSymbol(symbolMap map[string]*tkbtf.Symbol) {
 			tkbtf.NewFetchArg("mid", tkbtf.BitFieldTypeMask(fsEventIsDir)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmt", tkbtf.BitFieldTypeMask(fsEventMovedTo)).FuncParamWithName("mask"),
 			tkbtf.NewFetchArg("mmf", tkbtf.BitFieldTypeMask(fsEventMovedFrom)).FuncParamWithName("mask"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithName("dentry", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithName("dentry", "d_inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithName("dentry", "d_inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithName("dentry", "d_inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithName("dentry", "d_inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithName("dentry", "d_inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithName("dentry", "d_inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithName("dentry", "d_inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithName("dentry", "d_inode", "i_ctime", "tv_sec").FuncParamWithName("dentry", "d_inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithName("dentry", "d_inode", "i_ctime", "tv_nsec").FuncParamWithName("dentry", "d_inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithName("dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithName("dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithName("dentry", "d_name", "name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithName("dentry", "d_parent", "d_inode"))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithName("dentry", "d_inode"))...,
 		),
 	)
 
@@ -183,23 +156,15 @@ func loadFSNotifyParentSymbol(symbolMap map[string]*tkbtf.Symbol) {
 }
 
 func loadFSNotifyNameRemoveSymbol(symbolMap map[string]*tkbtf.Symbol) {
-	fsNotifyNameRemoveSymbol := tkbtf.NewSymbol("fsnotify_nameremove").AddProbes(
+	// fsnotify_nameremove reports the deletions only in kernels before 5.3, thus it is optional
+	fsNotifyNameRemoveSymbol := tkbtf.NewSymbol("fsnotify_nameremove").SetOptional(true).AddProbes(
 		tkbtf.NewKProbe().AddFetchArgs(
-			tkbtf.NewFetchArg("pi", "u64").FuncParamWithName("dentry", "d_parent", "d_inode", "i_ino"),
 			tkbtf.NewFetchArg("mid", "u32").FuncParamWithName("isdir"),
-			tkbtf.NewFetchArg("fi", "u64").FuncParamWithName("dentry", "d_inode", "i_ino"),
-			tkbtf.NewFetchArg("fm", "u8").FuncParamWithName("dentry", "d_inode", "i_mode"),
-			tkbtf.NewFetchArg("fuid", "u32").FuncParamWithName("dentry", "d_inode", "i_uid"),
-			tkbtf.NewFetchArg("fgid", "u32").FuncParamWithName("dentry", "d_inode", "i_gid"),
-			tkbtf.NewFetchArg("fats", "u64").FuncParamWithName("dentry", "d_inode", "i_atime", "tv_sec"),
-			tkbtf.NewFetchArg("fatn", "u64").FuncParamWithName("dentry", "d_inode", "i_atime", "tv_nsec"),
-			tkbtf.NewFetchArg("fmts", "u64").FuncParamWithName("dentry", "d_inode", "i_mtime", "tv_sec"),
-			tkbtf.NewFetchArg("fmtn", "u64").FuncParamWithName("dentry", "d_inode", "i_mtime", "tv_nsec"),
-			tkbtf.NewFetchArg("fcts", "u64").FuncParamWithName("dentry", "d_inode", "i_ctime", "tv_sec").FuncParamWithName("dentry", "d_inode", "__i_ctime", "tv_sec"),
-			tkbtf.NewFetchArg("fctn", "u64").FuncParamWithName("dentry", "d_inode", "i_ctime", "tv_nsec").FuncParamWithName("dentry", "d_inode", "__i_ctime", "tv_nsec"),
-			tkbtf.NewFetchArg("pdmj", tkbtf.BitFieldTypeMask(devMajor)).FuncParamWithName("dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
-			tkbtf.NewFetchArg("pdmn", tkbtf.BitFieldTypeMask(devMinor)).FuncParamWithName("dentry", "d_parent", "d_inode", "i_sb", "s_dev"),
 			tkbtf.NewFetchArg("fn", "string").FuncParamWithName("dentry", "d_name", "name"),
+		).AddFetchArgs(
+			parentInodeTemplate.Instantiate("p", tkbtf.NewTemplateBase().FuncParamWithName("dentry", "d_parent", "d_inode"))...,
+		).AddFetchArgs(
+			fileInodeTemplate.Instantiate("f", tkbtf.NewTemplateBase().FuncParamWithName("dentry", "d_inode"))...,
 		),
 	)
 
@@ -225,6 +190,11 @@ func main() {
 	loadFSNotifyNameRemoveSymbol(symbolMap)
 	loadVFSGetAttr(symbolMap)
 
+	symbols := make([]*tkbtf.Symbol, 0, len(symbolMap))
+	for _, symbol := range symbolMap {
+		symbols = append(symbols, symbol)
+	}
+
 	probesTracingMap := make(map[string]struct{})
 
 	err := filepath.Walk(btfHubArchiveRepoPath, func(path string, info fs.FileInfo, fnErr error) error {
@@ -242,25 +212,16 @@ func main() {
 			logger.Warn("error loading spec", slog.String("path", path), slog.Any("fnErr", fnErr))
 			return nil
 		}
+		spec.AddFieldAlias("inode", "i_ctime", "__i_ctime")
 
-		var symbolsToKeep []*tkbtf.Symbol
-		var newTracingProbe bool
-		for symbolName, symbol := range symbolMap {
-			fnErr = spec.BuildSymbol(symbol)
-			if fnErr != nil {
-				switch {
-				case symbolName == "fsnotify_nameremove" && errors.Is(fnErr, tkbtf.ErrSymbolNotFound):
-					continue
-				case symbolName == "vfs_getattr_nosec" && errors.Is(fnErr, tkbtf.ErrSymbolNotFound):
-					continue
-				default:
-					logger.Warn("error building symbol", slog.String("path", path), slog.String("symbol", symbolName), slog.Any("fnErr", fnErr))
-					continue
-				}
-			}
-
-			symbolsToKeep = append(symbolsToKeep, symbol)
+		result, fnErr := spec.BuildSymbols(symbols...)
+		if fnErr != nil {
+			logger.Warn("error building symbols", slog.String("path", path), slog.Any("fnErr", fnErr))
+		}
 
+		symbolsToKeep := result.GetBuiltSymbols()
+		var newTracingProbe bool
+		for _, symbol := range symbolsToKeep {
 			for _, p := range symbol.GetProbes() {
 				probeKey := p.GetSymbolName() + p.GetTracingEventProbe() + p.GetTracingEventFilter()
 
@@ -0,0 +1,168 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	tkbtf "github.com/elastic/tk-btf"
+)
+
+// newFSNotifySpec returns a minimal BTF spec with the layouts that the fsnotify_parent and fsnotify_nameremove
+// symbols resolve through. When withNameRemove is true, fsnotify_nameremove is present, as in kernels before 5.3.
+func newFSNotifySpec(t *testing.T, withNameRemove bool) *tkbtf.Spec {
+	t.Helper()
+
+	u16Type := &btf.Int{Name: "short unsigned int", Size: 2}
+	u32Type := &btf.Int{Name: "unsigned int", Size: 4}
+	u64Type := &btf.Int{Name: "long long unsigned int", Size: 8}
+	intType := &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}
+	charType := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+
+	timespec := &btf.Struct{
+		Name: "timespec64",
+		Size: 16,
+		Members: []btf.Member{
+			{Name: "tv_sec", Type: u64Type, Offset: 0},
+			{Name: "tv_nsec", Type: u64Type, Offset: 64},
+		},
+	}
+	superBlock := &btf.Struct{
+		Name:    "super_block",
+		Size:    4,
+		Members: []btf.Member{{Name: "s_dev", Type: u32Type, Offset: 0}},
+	}
+	inode := &btf.Struct{
+		Name: "inode",
+		Size: 136,
+		Members: []btf.Member{
+			{Name: "i_mode", Type: u16Type, Offset: 0},
+			{Name: "i_uid", Type: u32Type, Offset: 32},
+			{Name: "i_gid", Type: u32Type, Offset: 64},
+			{Name: "i_sb", Type: &btf.Pointer{Target: superBlock}, Offset: 320},
+			{Name: "i_ino", Type: u64Type, Offset: 512},
+			{Name: "i_atime", Type: timespec, Offset: 576},
+			{Name: "i_mtime", Type: timespec, Offset: 704},
+			{Name: "i_ctime", Type: timespec, Offset: 832},
+		},
+	}
+	qstr := &btf.Struct{
+		Name:    "qstr",
+		Size:    16,
+		Members: []btf.Member{{Name: "name", Type: &btf.Pointer{Target: &btf.Const{Type: charType}}, Offset: 64}},
+	}
+	dentry := &btf.Struct{Name: "dentry", Size: 56}
+	dentry.Members = []btf.Member{
+		{Name: "d_parent", Type: &btf.Pointer{Target: dentry}, Offset: 192},
+		{Name: "d_name", Type: qstr, Offset: 256},
+		{Name: "d_inode", Type: &btf.Pointer{Target: inode}, Offset: 384},
+	}
+
+	types := []btf.Type{
+		&btf.Func{
+			Name: "__fsnotify_parent",
+			Type: &btf.FuncProto{
+				Return: intType,
+				Params: []btf.FuncParam{
+					{Name: "dentry", Type: &btf.Pointer{Target: dentry}},
+					{Name: "mask", Type: u32Type},
+				},
+			},
+			Linkage: btf.GlobalFunc,
+		},
+	}
+	if withNameRemove {
+		types = append(types, &btf.Func{
+			Name: "fsnotify_nameremove",
+			Type: &btf.FuncProto{
+				Return: &btf.Void{},
+				Params: []btf.FuncParam{
+					{Name: "dentry", Type: &btf.Pointer{Target: dentry}},
+					{Name: "isdir", Type: intType},
+				},
+			},
+			Linkage: btf.GlobalFunc,
+		})
+	}
+
+	builder, err := btf.NewBuilder(types)
+	require.NoError(t, err)
+	raw, err := builder.Marshal(nil, nil)
+	require.NoError(t, err)
+
+	spec, err := tkbtf.NewSpecFromReader(bytes.NewReader(raw), nil)
+	if errors.Is(err, tkbtf.ErrUnsupportedArch) {
+		t.Skip("the architecture is not supported")
+	}
+	require.NoError(t, err)
+
+	return spec
+}
+
+func TestFSNotifyParentSymbols(t *testing.T) {
+	cases := []struct {
+		name           string
+		withNameRemove bool
+		built          []string
+		skipNameRemove bool
+	}{
+		{
+			name:           "before_5.3",
+			withNameRemove: true,
+			built:          []string{"__fsnotify_parent", "fsnotify_nameremove"},
+		},
+		{
+			name:           "since_5.3",
+			built:          []string{"__fsnotify_parent"},
+			skipNameRemove: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			spec := newFSNotifySpec(t, c.withNameRemove)
+
+			symbolMap := make(map[string]*tkbtf.Symbol)
+			loadFSNotifyParentSymbol(symbolMap)
+			loadFSNotifyNameRemoveSymbol(symbolMap)
+
+			result, err := spec.BuildSymbols(symbolMap["fsnotify_parent"], symbolMap["fsnotify_nameremove"])
+			require.NoError(t, err)
+
+			var built []string
+			for _, symbol := range result.GetBuiltSymbols() {
+				for _, p := range symbol.GetProbes() {
+					require.NotEmpty(t, p.GetTracingEventProbe())
+					built = append(built, p.GetSymbolName())
+				}
+			}
+			require.Equal(t, c.built, built)
+
+			if c.skipNameRemove {
+				require.Equal(t, []*tkbtf.Symbol{symbolMap["fsnotify_nameremove"]}, result.GetSkippedSymbols())
+			} else {
+				require.Empty(t, result.GetSkippedSymbols())
+			}
+		})
+	}
+}
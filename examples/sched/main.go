@@ -32,52 +32,29 @@ import (
 func loadWakeUpNewTaskSymbol(symbolMap map[string]*tkbtf.Symbol) {
 	wakeUpNewTaskSymbol := tkbtf.NewSymbol("wake_up_new_task").AddProbes(
 		tkbtf.NewKProbe().SetRef("wake_up_new_task").AddFetchArgs(
-			tkbtf.NewFetchArg("tid", "s32").FuncParamWithName("p", "pid").FuncParamWithName("p", "", "pid"),
-			tkbtf.NewFetchArg("tgid", "s32").FuncParamWithName("p", "tgid").FuncParamWithName("p", "", "tgid"),
-			tkbtf.NewFetchArg("ppid", "s32").FuncParamWithName("p", "group_leader", "real_parent", "tgid").FuncParamWithName("p", "", "group_leader", "", "real_parent", "", "tgid"),
-			tkbtf.NewFetchArg("stime", "u64").
-				FuncParamWithName("p", "group_leader", "start_time").
-				FuncParamWithName("p", "", "group_leader", "", "start_time"),
-			tkbtf.NewFetchArg("pgid", "s32").
+			tkbtf.NewFetchArg("tid", tkbtf.FetchArgTypeAuto).FuncParamWithName("p", "pid"),
+			tkbtf.NewFetchArg("tgid", tkbtf.FetchArgTypeAuto).FuncParamWithName("p", "tgid"),
+			tkbtf.NewFetchArg("ppid", tkbtf.FetchArgTypeAuto).FuncParamWithName("p", "group_leader", "real_parent", "tgid"),
+			tkbtf.NewFetchArg("stime", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "group_leader", "start_time"),
+			tkbtf.NewFetchArg("pgid", tkbtf.FetchArgTypeAuto).
 				FuncParamWithName("p", "group_leader", "pids", "enum:pid_type:PIDTYPE_PGID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "", "group_leader", "", "pids", "enum:pid_type:PIDTYPE_PGID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "", "group_leader", "", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr"),
-			tkbtf.NewFetchArg("sid", "s32").
+				FuncParamWithName("p", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr"),
+			tkbtf.NewFetchArg("sid", tkbtf.FetchArgTypeAuto).
 				FuncParamWithName("p", "group_leader", "pids", "enum:pid_type:PIDTYPE_SID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "", "group_leader", "", "pids", "enum:pid_type:PIDTYPE_SID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr").
-				FuncParamWithName("p", "", "group_leader", "", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr"),
-			tkbtf.NewFetchArg("cuid", "u32").
-				FuncParamWithName("p", "cred", "uid", "val").
-				FuncParamWithName("p", "", "cred", "uid", "val").
-				FuncParamWithName("p", "cred", "uid").
-				FuncParamWithName("p", "", "cred", "uid"),
-			tkbtf.NewFetchArg("cgid", "u32").
-				FuncParamWithName("p", "cred", "gid", "val").
-				FuncParamWithName("p", "", "cred", "gid", "val").
-				FuncParamWithName("p", "cred", "gid").
-				FuncParamWithName("p", "", "cred", "gid"),
-			tkbtf.NewFetchArg("ceuid", "u32").
-				FuncParamWithName("p", "cred", "euid", "val").
-				FuncParamWithName("p", "", "cred", "euid", "val").
-				FuncParamWithName("p", "cred", "euid").
-				FuncParamWithName("p", "", "cred", "euid"),
-			tkbtf.NewFetchArg("cegid", "u32").
-				FuncParamWithName("p", "cred", "egid", "val").
-				FuncParamWithName("p", "", "cred", "egid", "val").
-				FuncParamWithName("p", "cred", "egid").
-				FuncParamWithName("p", "", "cred", "egid"),
-			tkbtf.NewFetchArg("csuid", "u32").
-				FuncParamWithName("p", "cred", "suid", "val").
-				FuncParamWithName("p", "", "cred", "suid", "val").
-				FuncParamWithName("p", "cred", "suid").
-				FuncParamWithName("p", "", "cred", "suid"),
-			tkbtf.NewFetchArg("csgid", "u32").
-				FuncParamWithName("p", "cred", "sgid", "val").
-				FuncParamWithName("p", "", "cred", "sgid", "val").
-				FuncParamWithName("p", "cred", "sgid").
-				FuncParamWithName("p", "", "cred", "sgid"),
+				FuncParamWithName("p", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr"),
+			tkbtf.NewFetchArg("cuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "uid", "val"),
+			tkbtf.NewFetchArg("cgid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "gid", "val"),
+			tkbtf.NewFetchArg("ceuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "euid", "val"),
+			tkbtf.NewFetchArg("cegid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "egid", "val"),
+			tkbtf.NewFetchArg("csuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "suid", "val"),
+			tkbtf.NewFetchArg("csgid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("p", "cred", "sgid", "val"),
 		),
 	)
 
@@ -87,59 +64,43 @@ func loadWakeUpNewTaskSymbol(symbolMap map[string]*tkbtf.Symbol) {
 func loadTaskStatsExitSymbol(symbolMap map[string]*tkbtf.Symbol) {
 	taskStatsExitSymbol := tkbtf.NewSymbol("taskstats_exit").AddProbes(
 		tkbtf.NewKProbe().SetRef("taskstats_exit").AddFetchArgs(
-			tkbtf.NewFetchArg("tid", "s32").FuncParamWithName("tsk", "pid").FuncParamWithName("tsk", "", "pid"),
-			tkbtf.NewFetchArg("tgid", "s32").FuncParamWithName("tsk", "tgid").FuncParamWithName("tsk", "", "tgid"),
-			tkbtf.NewFetchArg("ppid", "s32").FuncParamWithName("tsk", "group_leader", "real_parent", "tgid").FuncParamWithName("tsk", "", "group_leader", "", "real_parent", "", "tgid"),
-			tkbtf.NewFetchArg("stime", "u64").
-				FuncParamWithName("tsk", "group_leader", "start_time").
-				FuncParamWithName("tsk", "", "group_leader", "", "start_time"),
-			tkbtf.NewFetchArg("pgid", "s32").
+			tkbtf.NewFetchArg("tid", tkbtf.FetchArgTypeAuto).FuncParamWithName("tsk", "pid"),
+			tkbtf.NewFetchArg("tgid", tkbtf.FetchArgTypeAuto).FuncParamWithName("tsk", "tgid"),
+			tkbtf.NewFetchArg("ppid", tkbtf.FetchArgTypeAuto).FuncParamWithName("tsk", "group_leader", "real_parent", "tgid"),
+			tkbtf.NewFetchArg("stime", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "group_leader", "start_time"),
+			tkbtf.NewFetchArg("pgid", tkbtf.FetchArgTypeAuto).
 				FuncParamWithName("tsk", "group_leader", "pids", "enum:pid_type:PIDTYPE_PGID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "", "group_leader", "", "pids", "enum:pid_type:PIDTYPE_PGID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "", "group_leader", "", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr"),
-			tkbtf.NewFetchArg("sid", "s32").
+				FuncParamWithName("tsk", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_PGID", "numbers", "index:0", "nr"),
+			tkbtf.NewFetchArg("sid", tkbtf.FetchArgTypeAuto).
 				FuncParamWithName("tsk", "group_leader", "pids", "enum:pid_type:PIDTYPE_SID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "", "group_leader", "", "pids", "enum:pid_type:PIDTYPE_SID", "pid", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr").
-				FuncParamWithName("tsk", "", "group_leader", "", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr"),
-			tkbtf.NewFetchArg("gd", "s32").FuncParamWithName("group_dead"),
-			tkbtf.NewFetchArg("cuid", "u32").
-				FuncParamWithName("tsk", "cred", "uid", "val").
-				FuncParamWithName("tsk", "", "cred", "uid", "val").
-				FuncParamWithName("tsk", "cred", "uid").
-				FuncParamWithName("tsk", "", "cred", "uid"),
-			tkbtf.NewFetchArg("cgid", "u32").
-				FuncParamWithName("tsk", "cred", "gid", "val").
-				FuncParamWithName("tsk", "", "cred", "gid", "val").
-				FuncParamWithName("tsk", "cred", "gid").
-				FuncParamWithName("tsk", "", "cred", "gid"),
-			tkbtf.NewFetchArg("ceuid", "u32").
-				FuncParamWithName("tsk", "cred", "euid", "val").
-				FuncParamWithName("tsk", "", "cred", "euid", "val").
-				FuncParamWithName("tsk", "cred", "euid").
-				FuncParamWithName("tsk", "", "cred", "euid"),
-			tkbtf.NewFetchArg("cegid", "u32").
-				FuncParamWithName("tsk", "cred", "egid", "val").
-				FuncParamWithName("tsk", "", "cred", "egid", "val").
-				FuncParamWithName("tsk", "cred", "egid").
-				FuncParamWithName("tsk", "", "cred", "egid"),
-			tkbtf.NewFetchArg("csuid", "u32").
-				FuncParamWithName("tsk", "cred", "suid", "val").
-				FuncParamWithName("tsk", "", "cred", "suid", "val").
-				FuncParamWithName("tsk", "cred", "suid").
-				FuncParamWithName("tsk", "", "cred", "suid"),
-			tkbtf.NewFetchArg("csgid", "u32").
-				FuncParamWithName("tsk", "cred", "sgid", "val").
-				FuncParamWithName("tsk", "", "cred", "sgid", "val").
-				FuncParamWithName("tsk", "cred", "sgid").
-				FuncParamWithName("tsk", "", "cred", "sgid"),
+				FuncParamWithName("tsk", "group_leader", "signal", "pids", "enum:pid_type:PIDTYPE_SID", "numbers", "index:0", "nr"),
+			tkbtf.NewFetchArg("gd", tkbtf.FetchArgTypeAuto).FuncParamWithName("group_dead"),
+			tkbtf.NewFetchArg("cuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "uid", "val"),
+			tkbtf.NewFetchArg("cgid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "gid", "val"),
+			tkbtf.NewFetchArg("ceuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "euid", "val"),
+			tkbtf.NewFetchArg("cegid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "egid", "val"),
+			tkbtf.NewFetchArg("csuid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "suid", "val"),
+			tkbtf.NewFetchArg("csgid", tkbtf.FetchArgTypeAuto).
+				FuncParamWithName("tsk", "cred", "sgid", "val"),
 		),
 	)
 
 	symbolMap["taskstats_exit"] = taskStatsExitSymbol
 }
 
+// addFieldAliases registers the field aliases that let the same fetch args resolve against older kernels,
+// e.g. the cred ids that are plain uid_t and gid_t instead of kuid_t and kgid_t.
+func addFieldAliases(spec *tkbtf.Spec) {
+	spec.AddFieldAlias("uid_t", "val", "")
+	spec.AddFieldAlias("gid_t", "val", "")
+}
+
 func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	symbolMap := make(map[string]*tkbtf.Symbol)
@@ -159,6 +120,11 @@ func main() {
 	loadWakeUpNewTaskSymbol(symbolMap)
 	loadTaskStatsExitSymbol(symbolMap)
 
+	symbols := make([]*tkbtf.Symbol, 0, len(symbolMap))
+	for _, symbol := range symbolMap {
+		symbols = append(symbols, symbol)
+	}
+
 	probesTracingMap := make(map[string]struct{})
 
 	seenBTFnames := make(map[string]interface{})
@@ -178,18 +144,17 @@ func main() {
 			logger.Warn("error loading spec", slog.String("path", path), slog.Any("err", err))
 			return nil
 		}
+		spec.SetAnonymousMemberLookup(true)
+		addFieldAliases(spec)
 
-		var symbolsToKeep []*tkbtf.Symbol
-		var newTracingProbe bool
-		for symbolName, symbol := range symbolMap {
-			err = spec.BuildSymbol(symbol)
-			if err != nil {
-				logger.Warn("error building symbol", slog.String("path", path), slog.String("symbol", symbolName), slog.Any("err", err))
-				continue
-			}
-
-			symbolsToKeep = append(symbolsToKeep, symbol)
+		result, err := spec.BuildSymbols(symbols...)
+		if err != nil {
+			logger.Warn("error building symbols", slog.String("path", path), slog.Any("err", err))
+		}
 
+		symbolsToKeep := result.GetBuiltSymbols()
+		var newTracingProbe bool
+		for _, symbol := range symbolsToKeep {
 			for _, p := range symbol.GetProbes() {
 				probeKey := p.GetSymbolName() + p.GetTracingEventProbe() + p.GetTracingEventFilter()
 
@@ -227,12 +192,11 @@ func main() {
 			logger.Warn("error loading spec from stripped btf", slog.String("path", strippedSpecPath), slog.Any("err", err))
 			return nil
 		}
+		strippedSpec.SetAnonymousMemberLookup(true)
+		addFieldAliases(strippedSpec)
 
-		for symbolName, symbol := range symbolMap {
-			if err := strippedSpec.BuildSymbol(symbol); err != nil {
-				logger.Warn("error building symbol", slog.String("path", strippedSpecPath), slog.String("symbol", symbolName), slog.Any("err", err))
-				continue
-			}
+		if _, err := strippedSpec.BuildSymbols(symbols...); err != nil {
+			logger.Warn("error building symbols", slog.String("path", strippedSpecPath), slog.Any("err", err))
 		}
 
 		return nil
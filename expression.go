@@ -0,0 +1,490 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// ExpressionError describes why a field path expression failed to parse. Column is the position, starting from 1,
+// of the character of the expression where parsing failed.
+type ExpressionError struct {
+	Expression string
+	Column     int
+	Msg        string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("invalid expression %q at column %d: %s", e.Expression, e.Column, e.Msg)
+}
+
+// Unwrap returns ErrInvalidExpression so that errors.Is can match any ExpressionError.
+func (e *ExpressionError) Unwrap() error {
+	return ErrInvalidExpression
+}
+
+type expressionRoot uint32
+
+const (
+	// expressionRootParam the root of the expression is a function parameter specified by name.
+	expressionRootParam expressionRoot = iota
+	// expressionRootArg the root of the expression is a function parameter specified by index, e.g. $arg1.
+	expressionRootArg
+	// expressionRootRetval the root of the expression is the function return value, namely $retval.
+	expressionRootRetval
+	// expressionRootGlobal the root of the expression is a kernel global variable, e.g. @init_pid_ns.
+	expressionRootGlobal
+)
+
+// expressionCast holds the type name and the respective Wrap of a cast, e.g. (struct path *).
+type expressionCast struct {
+	typeName string
+	wrap     Wrap
+}
+
+// expression is the parsed representation of a field path expression.
+type expression struct {
+	root       expressionRoot
+	paramName  string
+	paramIndex int
+	cast       *expressionCast
+	fields     []string
+}
+
+// expressionParser is a hand-written recursive descent parser of field path expressions.
+type expressionParser struct {
+	expr string
+	pos  int
+}
+
+// parseExpression parses a C-like field path expression, e.g. p->group_leader->pids[PIDTYPE_PGID].pid->nr, into
+// the fields chain that the fieldsBuilders process. The grammar of an expression is the following:
+//
+//	expression := primary { accessor }
+//	primary    := root [ "(" cast ")" ] | "container_of" "(" expression "," container "," identifier ")"
+//	root       := identifier | "$arg" number | "$retval" | "@" identifier
+//	cast       := [ "struct" | "union" | "enum" ] identifier { identifier } [ "__user" ] { "*" }
+//	container  := [ "struct" | "union" ] identifier
+//	accessor   := "->" identifier | "." identifier | "[" ( number | identifier ) "]"
+//
+// Array indexes that are identifiers are treated as enum values, which are searched by name in the btf spec.
+func parseExpression(expr string) (*expression, error) {
+	p := &expressionParser{expr: expr}
+
+	e, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if !p.eof() {
+		return nil, p.errorf(p.pos, "unexpected character %q, expected '->', '.' or '['", p.peek())
+	}
+
+	return e, nil
+}
+
+// parseExpression parses a primary followed by any accessors.
+func (p *expressionParser) parseExpression() (*expression, error) {
+	e, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		p.skipSpaces()
+		if !p.atAccessor() {
+			return e, nil
+		}
+
+		field, err := p.parseAccessor()
+		if err != nil {
+			return nil, err
+		}
+
+		e.fields = append(e.fields, field)
+	}
+}
+
+// parsePrimary parses either a root with an optional cast or a container_of.
+func (p *expressionParser) parsePrimary() (*expression, error) {
+	p.skipSpaces()
+
+	if p.atContainerOf() {
+		return p.parseContainerOf()
+	}
+
+	e, err := p.parseRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.peek() == '(' {
+		p.pos++
+		if e.cast, err = p.parseCast(); err != nil {
+			return nil, err
+		}
+	}
+
+	if e.root == expressionRootArg && e.cast == nil {
+		return nil, p.errorf(p.pos, "$arg%d requires a type cast", e.paramIndex+1)
+	}
+
+	if e.root == expressionRootGlobal && e.cast != nil {
+		return nil, p.errorf(p.pos, "global variable @%s can't be cast", e.paramName)
+	}
+
+	return e, nil
+}
+
+// atContainerOf returns true if a container_of starts at the current position.
+func (p *expressionParser) atContainerOf() bool {
+	const keyword = "container_of"
+	if !strings.HasPrefix(p.expr[p.pos:], keyword) {
+		return false
+	}
+
+	rest := strings.TrimLeft(p.expr[p.pos+len(keyword):], " \t")
+	return strings.HasPrefix(rest, "(")
+}
+
+// parseContainerOf parses a container_of up to and including the closing parenthesis.
+func (p *expressionParser) parseContainerOf() (*expression, error) {
+	p.identifier()
+	p.skipSpaces()
+	// skip the opening parenthesis
+	p.pos++
+
+	e, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	containerStart := p.pos
+	containerName := p.identifier()
+	if containerName == "struct" || containerName == "union" {
+		p.skipSpaces()
+		containerName = p.identifier()
+	}
+	if containerName == "" {
+		return nil, p.errorf(containerStart, "expected the container type name")
+	}
+
+	if err := p.expect(','); err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	memberStart := p.pos
+	memberName := p.identifier()
+	if memberName == "" {
+		return nil, p.errorf(memberStart, "expected the container member name")
+	}
+
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+
+	e.fields = append(e.fields, ContainerOf(containerName, memberName))
+	return e, nil
+}
+
+// expect consumes the given character, after skipping any spaces, or returns an error if it's missing.
+func (p *expressionParser) expect(c byte) error {
+	p.skipSpaces()
+	if p.peek() != c {
+		return p.errorf(p.pos, "expected '%c'", c)
+	}
+
+	p.pos++
+	return nil
+}
+
+// atAccessor returns true if an accessor starts at the current position.
+func (p *expressionParser) atAccessor() bool {
+	return strings.HasPrefix(p.expr[p.pos:], "->") || p.peek() == '.' || p.peek() == '['
+}
+
+// parseRoot parses the root of the expression.
+func (p *expressionParser) parseRoot() (*expression, error) {
+	p.skipSpaces()
+
+	if p.peek() == '@' {
+		start := p.pos
+		p.pos++
+		name := p.identifier()
+		if name == "" {
+			return nil, p.errorf(start, "expected a global variable name after '@'")
+		}
+
+		return &expression{
+			root:      expressionRootGlobal,
+			paramName: name,
+		}, nil
+	}
+
+	if p.peek() != '$' {
+		start := p.pos
+		name := p.identifier()
+		if name == "" {
+			return nil, p.errorf(start, "expected a function parameter name")
+		}
+
+		return &expression{
+			root:      expressionRootParam,
+			paramName: name,
+		}, nil
+	}
+
+	start := p.pos
+	p.pos++
+	variable := p.identifier()
+
+	switch {
+	case variable == "retval":
+		return &expression{
+			root: expressionRootRetval,
+		}, nil
+	case strings.HasPrefix(variable, "arg"):
+		index, err := strconv.Atoi(strings.TrimPrefix(variable, "arg"))
+		if err != nil || index < 1 {
+			return nil, p.errorf(start, "invalid function parameter $%s, expected $arg followed by a number starting from 1", variable)
+		}
+
+		return &expression{
+			root:       expressionRootArg,
+			paramIndex: index - 1,
+		}, nil
+	default:
+		return nil, p.errorf(start, "unsupported variable $%s, expected $argN or $retval", variable)
+	}
+}
+
+// parseCast parses the cast up to and including the closing parenthesis.
+func (p *expressionParser) parseCast() (*expressionCast, error) {
+	var nameTokens []string
+	var userSpace bool
+	pointers := 0
+
+	castStart := p.pos
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return nil, p.errorf(p.pos, "expected ')' to close the cast")
+		}
+
+		start := p.pos
+		switch c := p.peek(); {
+		case c == ')':
+			p.pos++
+			return newExpressionCast(p, castStart, nameTokens, userSpace, pointers)
+		case c == '*':
+			p.pos++
+			pointers++
+		case isIdentifierStart(c):
+			if pointers > 0 {
+				return nil, p.errorf(start, "unexpected type name after '*'")
+			}
+
+			token := p.identifier()
+			switch token {
+			case "struct", "union", "enum":
+				if len(nameTokens) > 0 {
+					return nil, p.errorf(start, "unexpected keyword %s", token)
+				}
+			case "const", "volatile", "restrict":
+			case "__user":
+				userSpace = true
+			default:
+				nameTokens = append(nameTokens, token)
+			}
+		default:
+			return nil, p.errorf(start, "unexpected character %q in cast", c)
+		}
+	}
+}
+
+// newExpressionCast maps the parsed cast to the respective type name and Wrap.
+func newExpressionCast(p *expressionParser, start int, nameTokens []string, userSpace bool, pointers int) (*expressionCast, error) {
+	if len(nameTokens) == 0 {
+		return nil, p.errorf(start, "missing type name in cast")
+	}
+
+	cast := &expressionCast{
+		typeName: strings.Join(nameTokens, " "),
+	}
+
+	switch {
+	case pointers == 0 && !userSpace:
+		cast.wrap = WrapNone
+	case pointers == 1 && userSpace:
+		cast.wrap = WrapUserPointer
+	case pointers == 1:
+		cast.wrap = WrapPointer
+	case pointers == 2 && !userSpace:
+		cast.wrap = WrapStructPointer
+	default:
+		return nil, p.errorf(start, "unsupported cast to %s, expected T, T *, T __user * or T **", cast.typeName)
+	}
+
+	return cast, nil
+}
+
+// parseAccessor parses a member access or an array index and returns the respective field.
+func (p *expressionParser) parseAccessor() (string, error) {
+	start := p.pos
+	switch {
+	case strings.HasPrefix(p.expr[p.pos:], "->"):
+		p.pos += 2
+	case p.peek() == '.':
+		p.pos++
+	case p.peek() == '[':
+		p.pos++
+		return p.parseIndex()
+	default:
+		return "", p.errorf(start, "unexpected character %q, expected '->', '.' or '['", p.peek())
+	}
+
+	p.skipSpaces()
+	nameStart := p.pos
+	name := p.identifier()
+	if name == "" {
+		return "", p.errorf(nameStart, "expected a field name")
+	}
+
+	return name, nil
+}
+
+// parseIndex parses an array index up to and including the closing bracket.
+func (p *expressionParser) parseIndex() (string, error) {
+	p.skipSpaces()
+
+	start := p.pos
+	token := p.identifier()
+	if token == "" {
+		return "", p.errorf(start, "expected an array index")
+	}
+
+	var field string
+	if token[0] >= '0' && token[0] <= '9' {
+		index, err := strconv.ParseUint(token, 0, 32)
+		if err != nil {
+			return "", p.errorf(start, "invalid array index %s", token)
+		}
+		field = fmt.Sprintf("index:%d", index)
+	} else {
+		field = "enum::" + token
+	}
+
+	p.skipSpaces()
+	if p.peek() != ']' {
+		return "", p.errorf(p.pos, "expected ']' to close the array index")
+	}
+	p.pos++
+
+	return field, nil
+}
+
+// identifier consumes and returns the identifier, or number, that starts at the current position.
+func (p *expressionParser) identifier() string {
+	start := p.pos
+	for !p.eof() && isIdentifierChar(p.peek()) {
+		p.pos++
+	}
+
+	return p.expr[start:p.pos]
+}
+
+// skipSpaces consumes any whitespace, so that e.g. "struct  inode*" and "struct\n\tinode *" parse the same as
+// "struct inode *".
+func (p *expressionParser) skipSpaces() {
+	for !p.eof() && isSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *expressionParser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+// peek returns the character at the current position or 0 if the end of the expression is reached.
+func (p *expressionParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+
+	return p.expr[p.pos]
+}
+
+// errorf returns an ExpressionError for the given position of the expression.
+func (p *expressionParser) errorf(pos int, format string, args ...any) error {
+	return &ExpressionError{
+		Expression: p.expr,
+		Column:     pos + 1,
+		Msg:        fmt.Sprintf(format, args...),
+	}
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+// invalidExpression is a fieldsBuilder that holds the error of an expression that failed to parse, so that the error
+// is returned when the fetchArg is built.
+type invalidExpression struct {
+	err error
+}
+
+func (p *invalidExpression) build(_ btfSpec, _ resolveOptions, _ ProbeType, _ *btf.Func, _ registersResolver) (string, error) {
+	return "", p.err
+}
+
+func (p *invalidExpression) getFields() []*field {
+	return nil
+}
+
+func (p *invalidExpression) getWrap() Wrap {
+	return WrapNone
+}
+
+func (p *invalidExpression) getLeafType() btf.Type {
+	return nil
+}
@@ -0,0 +1,236 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseExpression(t *testing.T) {
+	cases := []struct {
+		name     string
+		expr     string
+		expected *expression
+		column   int
+	}{
+		{
+			name: "param_members",
+			expr: "p->group_leader->pids[PIDTYPE_PGID].pid->numbers[0].nr",
+			expected: &expression{
+				root:      expressionRootParam,
+				paramName: "p",
+				fields:    []string{"group_leader", "pids", "enum::PIDTYPE_PGID", "pid", "numbers", "index:0", "nr"},
+			},
+		},
+		{
+			name: "param_without_fields",
+			expr: "group_dead",
+			expected: &expression{
+				root:      expressionRootParam,
+				paramName: "group_dead",
+			},
+		},
+		{
+			name: "param_pointer_cast",
+			expr: "data(struct path *)->dentry->d_name.name",
+			expected: &expression{
+				root:      expressionRootParam,
+				paramName: "data",
+				cast:      &expressionCast{typeName: "path", wrap: WrapPointer},
+				fields:    []string{"dentry", "d_name", "name"},
+			},
+		},
+		{
+			name: "arg_struct_pointer_cast",
+			expr: " $arg2 ( struct path ** ) -> dentry [ 0x10 ] ",
+			expected: &expression{
+				root:       expressionRootArg,
+				paramIndex: 1,
+				cast:       &expressionCast{typeName: "path", wrap: WrapStructPointer},
+				fields:     []string{"dentry", "index:16"},
+			},
+		},
+		{
+			name: "arg_user_pointer_cast",
+			expr: "$arg1(const char __user *)",
+			expected: &expression{
+				root:       expressionRootArg,
+				paramIndex: 0,
+				cast:       &expressionCast{typeName: "char", wrap: WrapUserPointer},
+			},
+		},
+		{
+			name: "cast_irregular_spacing",
+			expr: "$arg2(struct  inode*)->i_ino",
+			expected: &expression{
+				root:       expressionRootArg,
+				paramIndex: 1,
+				cast:       &expressionCast{typeName: "inode", wrap: WrapPointer},
+				fields:     []string{"i_ino"},
+			},
+		},
+		{
+			name: "cast_multi_line",
+			expr: "$arg1(const\n\tchar __user\r\n*)",
+			expected: &expression{
+				root:       expressionRootArg,
+				paramIndex: 0,
+				cast:       &expressionCast{typeName: "char", wrap: WrapUserPointer},
+			},
+		},
+		{
+			name: "retval",
+			expr: "$retval->d_inode->i_ino",
+			expected: &expression{
+				root:   expressionRootRetval,
+				fields: []string{"d_inode", "i_ino"},
+			},
+		},
+		{
+			name: "retval_multi_word_cast",
+			expr: "$retval(unsigned   int)",
+			expected: &expression{
+				root: expressionRootRetval,
+				cast: &expressionCast{typeName: "unsigned int", wrap: WrapNone},
+			},
+		},
+		{
+			name: "container_of",
+			expr: "container_of(node->next, struct task_struct, tasks)->pid",
+			expected: &expression{
+				root:      expressionRootParam,
+				paramName: "node",
+				fields:    []string{"next", "container_of:task_struct:tasks", "pid"},
+			},
+		},
+		{
+			name: "nested_container_of",
+			expr: "container_of( container_of($arg1(struct list_head *), task_struct, tasks)->tasks.prev , struct task_struct , tasks )",
+			expected: &expression{
+				root:       expressionRootArg,
+				paramIndex: 0,
+				cast:       &expressionCast{typeName: "list_head", wrap: WrapPointer},
+				fields:     []string{"container_of:task_struct:tasks", "tasks", "prev", "container_of:task_struct:tasks"},
+			},
+		},
+		{
+			name:   "container_of_missing_member",
+			expr:   "container_of(node, struct task_struct)->pid",
+			column: 38,
+		},
+		{
+			name:   "container_of_missing_type",
+			expr:   "container_of(node, , tasks)",
+			column: 20,
+		},
+		{
+			name: "global_variable",
+			expr: "@init_pid_ns.child_reaper->pid",
+			expected: &expression{
+				root:      expressionRootGlobal,
+				paramName: "init_pid_ns",
+				fields:    []string{"child_reaper", "pid"},
+			},
+		},
+		{
+			name:   "global_variable_cast",
+			expr:   "@init_pid_ns(struct pid_namespace *)",
+			column: 37,
+		},
+		{
+			name:   "global_variable_missing_name",
+			expr:   "@->pid",
+			column: 1,
+		},
+		{
+			name:   "empty",
+			expr:   "",
+			column: 1,
+		},
+		{
+			name:   "trailing_arrow",
+			expr:   "p->group_leader->",
+			column: 18,
+		},
+		{
+			name:   "unknown_accessor",
+			expr:   "p->group_leader:pid",
+			column: 16,
+		},
+		{
+			name:   "unclosed_index",
+			expr:   "p->numbers[0.nr",
+			column: 13,
+		},
+		{
+			name:   "invalid_index",
+			expr:   "p->numbers[0z]",
+			column: 12,
+		},
+		{
+			name:   "unclosed_cast",
+			expr:   "data(struct path *",
+			column: 19,
+		},
+		{
+			name:   "missing_cast_type",
+			expr:   "data(struct *)",
+			column: 6,
+		},
+		{
+			name:   "unsupported_cast",
+			expr:   "data(struct path ***)",
+			column: 6,
+		},
+		{
+			name:   "arg_without_cast",
+			expr:   "$arg1->dentry",
+			column: 6,
+		},
+		{
+			name:   "arg_zero",
+			expr:   "$arg0(struct path *)",
+			column: 1,
+		},
+		{
+			name:   "unsupported_variable",
+			expr:   "$comm",
+			column: 1,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e, err := parseExpression(c.expr)
+			if c.expected != nil {
+				require.NoError(t, err)
+				require.Equal(t, c.expected, e)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrInvalidExpression)
+			var exprErr *ExpressionError
+			require.True(t, errors.As(err, &exprErr))
+			require.Equal(t, c.column, exprErr.Column)
+			require.Equal(t, c.expr, exprErr.Expression)
+		})
+	}
+}
@@ -19,6 +19,8 @@ package tkbtf
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/cilium/ebpf/btf"
@@ -26,7 +28,8 @@ import (
 
 // Wrap indicates if and how the first field should be wrapped in fieldsBuilder that process the fields
 // without relying on the existence of the function prototype in the BTF spec, namely FuncParamWithCustomType,
-// FuncParamArbitrary and FuncReturnArbitrary.
+// FuncParamArbitrary and FuncReturnArbitrary. When the first field is the name of a function, e.g. vfs_read, the
+// function prototype is the type that gets wrapped, thus WrapPointer results in a function pointer.
 type Wrap uint32
 
 const (
@@ -37,16 +40,23 @@ const (
 	// WrapStructPointer the type will be wrapped as a member of an arbitrary struct at offset zero which is wrapped
 	// under a pointer.
 	WrapStructPointer
+	// WrapUserPointer the type will be wrapped under a pointer that targets to it in user-space memory, as if the
+	// pointer was annotated with __user. Fields reached through such pointers are fetched with user-space
+	// dereferences (+uN(...)) and strings with the ustring type. This is useful when the btf spec lacks the __user
+	// type tags; alternatively, specify explicitly the ustring type.
+	WrapUserPointer
 )
 
 // fieldsBuilder is an interface that abstracts all the different types of fieldsBuilder.
 type fieldsBuilder interface {
 	// build processes the fields.
-	build(spec btfSpec, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error)
+	build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error)
 	// getFields returns a slice of the fields.
 	getFields() []*field
 	// getWrap returns the wrap used.
 	getWrap() Wrap
+	// getLeafType returns the btf type of the value that the fieldsBuilder resolved to during build.
+	getLeafType() btf.Type
 }
 
 type fetchArg struct {
@@ -55,12 +65,14 @@ type fetchArg struct {
 	fBuilders         []fieldsBuilder
 	btfFunc           *btf.Func
 	successfulBuilder fieldsBuilder
+	info              *FetchArgInfo
+	optional          bool
 }
 
-// NewFetchArg creates and returns a new fetchArg with the given name and type. Note that
+// NewFetchArg creates and returns a new fetchArg with the given name and type, see also FetchArgTypeAuto. Note that
 // fetchArg requires fieldsBuilders to be attached to it which is done by the functions
-// FuncParamWithName, FuncParamArbitrary, and FuncParamWithCustomType for KProbes. Respectively,
-// for KRetProbes the fieldsBuilder functions are FuncReturn and FuncReturnArbitrary.
+// FuncParamWithName, FuncParamWithType, FuncParamArbitrary, and FuncParamWithCustomType for KProbes. Respectively,
+// for KRetProbes the fieldsBuilder functions are FuncReturn, FuncReturnWithType and FuncReturnArbitrary.
 // When a fetch arg is built without any fieldsBuilder attached, ErrMissingFieldBuilders is returned.
 // Also, that you can add multiple fieldsBuilders to the same fetchArg but the first one, in respect
 // to the order they were added, that is built without an error will satisfy the fetchArg.
@@ -72,6 +84,14 @@ func NewFetchArg(argName string, argType string) *fetchArg {
 	}
 }
 
+// SetOptional marks the fetchArg as optional. When none of the fieldsBuilders of an optional fetchArg builds
+// successfully, the fetchArg is omitted from the Probe instead of failing its build. The omitted fetchArgs are
+// reported by Probe.GetOmittedFetchArgs.
+func (f *fetchArg) SetOptional(optional bool) *fetchArg {
+	f.optional = optional
+	return f
+}
+
 // FuncParamWithName attaches a fieldsBuilder to the fetchArg that does require the function prototype
 // to be available in the BTF spec. Based on it, it extracts the parameter index and type that matches the given name
 // and then builds the fields as members of the former.
@@ -88,6 +108,26 @@ func (f *fetchArg) FuncParamWithName(paramName string, fields ...string) *fetchA
 	return f
 }
 
+// FuncParamWithType attaches a fieldsBuilder to the fetchArg that does require the function prototype
+// to be available in the BTF spec. Based on it, it extracts the parameter index and type of the given occurrence,
+// starting from zero, among the parameters whose type matches the given C type name, e.g. "struct inode *", and
+// then builds the fields as members of the former. This kind of fieldsBuilder is useful when the parameter names
+// change between kernel versions but their types stay the same. Qualifiers are ignored and the type name can refer
+// either to a typedef or the type it resolves to, while base types are named as in the BTF spec,
+// e.g. "long unsigned int".
+//
+// Note that FuncParamWithType is compatible only with ProbeTypeKProbe. If combined with any other type of Probe
+// it will return an ErrIncompatibleFetchArg error.
+func (f *fetchArg) FuncParamWithType(typeName string, occurrence int, fields ...string) *fetchArg {
+	f.fBuilders = append(f.fBuilders, &funcParamWithType{
+		foundIndex: -1,
+		typeName:   typeName,
+		occurrence: occurrence,
+		fields:     paramFieldsFromNames(fields...),
+	})
+	return f
+}
+
 // FuncParamArbitrary attaches a fieldsBuilder to the fetchArg that doesn't require the function prototype
 // to be available in the BTF spec. Instead, it utilises the given arbitrary parameter index to calculate
 // the respective architecture register, and it uses the first supplied field to determine the type of this
@@ -140,6 +180,23 @@ func (f *fetchArg) FuncReturn(fields ...string) *fetchArg {
 	return f
 }
 
+// FuncReturnWithType attaches a fieldsBuilder to the fetchArg that does require the function prototype
+// to be available in the BTF spec. It behaves as FuncReturn as long as the return type of the function matches
+// the given C type name, e.g. "struct dentry *", following the same rules as FuncParamWithType; otherwise it
+// returns an ErrFuncParamNotFound error, so that the next fieldsBuilder of the fetchArg can be tried.
+//
+// Note that FuncReturnWithType is compatible only with ProbeTypeKRetProbe. If combined with any other type
+// of Probe it will return an ErrIncompatibleFetchArg error.
+func (f *fetchArg) FuncReturnWithType(typeName string, fields ...string) *fetchArg {
+	f.fBuilders = append(f.fBuilders, &funcReturnWithType{
+		typeName: typeName,
+		funcReturn: funcReturn{
+			fields: paramFieldsFromNames(fields...),
+		},
+	})
+	return f
+}
+
 // FuncReturnArbitrary attaches a fieldsBuilder to the fetchArg that doesn't require the function prototype
 // to be available in the BTF spec. Instead, it utilises the first supplied field as the type of the function
 // return value. Then it processes the remaining fields as members of the former. When more arbitrary
@@ -157,11 +214,157 @@ func (f *fetchArg) FuncReturnArbitrary(wrap Wrap, fields ...string) *fetchArg {
 	return f
 }
 
+// FromExpression attaches to the fetchArg the fieldsBuilder that corresponds to the given C-like field path
+// expression, e.g. "p->group_leader->pids[PIDTYPE_PGID].pid->numbers[0].nr". The root of the expression determines
+// the fieldsBuilder:
+//   - "name" is equivalent to FuncParamWithName
+//   - "name(cast)" is equivalent to FuncParamWithCustomType
+//   - "$argN(cast)", where N starts from 1, is equivalent to FuncParamArbitrary
+//   - "$retval" and "$retval(cast)" are equivalent to FuncReturn and FuncReturnArbitrary respectively
+//   - "@name" is equivalent to GlobalVariable
+//
+// A cast stands in for the type name and the Wrap of the fieldsBuilders, namely "(struct path)" for WrapNone,
+// "(struct path *)" for WrapPointer, "(struct path __user *)" for WrapUserPointer and "(struct path **)" for
+// WrapStructPointer. Members are accessed with either "->" or "." since the btf types determine where a dereference
+// is needed, and array elements with "[N]" or "[ENUM_VALUE]". Embedded members can be stepped back to their container
+// with "container_of(expr, struct type, member)", see ContainerOf. If the expression can't be parsed, the fetchArg
+// returns an ExpressionError, which points at the failing column, when built.
+func (f *fetchArg) FromExpression(expr string) *fetchArg {
+	e, err := parseExpression(expr)
+	if err != nil {
+		f.fBuilders = append(f.fBuilders, &invalidExpression{err: err})
+		return f
+	}
+
+	fields := e.fields
+	if e.cast != nil {
+		fields = append([]string{e.cast.typeName}, fields...)
+	}
+
+	switch {
+	case e.root == expressionRootParam && e.cast == nil:
+		return f.FuncParamWithName(e.paramName, fields...)
+	case e.root == expressionRootParam:
+		return f.FuncParamWithCustomType(e.paramName, e.cast.wrap, fields...)
+	case e.root == expressionRootArg:
+		return f.FuncParamArbitrary(e.paramIndex, e.cast.wrap, fields...)
+	case e.root == expressionRootGlobal:
+		return f.GlobalVariable(e.paramName, fields...)
+	case e.root == expressionRootRetval && e.cast == nil:
+		return f.FuncReturn(fields...)
+	default:
+		return f.FuncReturnArbitrary(e.cast.wrap, fields...)
+	}
+}
+
+// GlobalVariable attaches a fieldsBuilder to the fetchArg that reads the kernel global variable with the given name,
+// e.g. init_pid_ns or jiffies, instead of a function parameter or return value. The type of the variable is resolved
+// through the btf Var and Datasec entries of the btf spec, and the fields are built as members of the former. The
+// resulting tracing fs representation reads memory relative to the symbol address, e.g. +8(@init_pid_ns+16).
+// When the variable is not found in the btf spec, an ErrGlobalVariableNotFound error is returned, and per-cpu
+// variables, which can't be read through their symbol address, result in an ErrUnsupportedGlobalVariable error.
+//
+// Note that GlobalVariable is compatible with any type of Probe.
+func (f *fetchArg) GlobalVariable(name string, fields ...string) *fetchArg {
+	f.fBuilders = append(f.fBuilders, &globalVariable{
+		name:   name,
+		fields: paramFieldsFromNames(append([]string{name}, fields...)...),
+	})
+	return f
+}
+
+// Comm attaches a fieldsBuilder to the fetchArg that fetches the name of the current task, namely $comm. With
+// FetchArgTypeAuto the fetchArg type is inferred as string.
+//
+// Note that Comm is compatible with any type of Probe.
+func (f *fetchArg) Comm() *fetchArg {
+	v := newFetchVariable("$comm", "string")
+	v.inPlace = true
+	f.fBuilders = append(f.fBuilders, v)
+	return f
+}
+
+// FuncReturnValue attaches a fieldsBuilder to the fetchArg that fetches the return value of the function, namely
+// $retval, without resolving any register. With FetchArgTypeAuto the fetchArg type is inferred from the function
+// prototype, when available in the BTF spec, otherwise it is x64.
+//
+// Note that FuncReturnValue is compatible only with ProbeTypeKRetProbe. If combined with any other type of Probe
+// it will return an ErrIncompatibleFetchArg error.
+func (f *fetchArg) FuncReturnValue() *fetchArg {
+	f.fBuilders = append(f.fBuilders, newFetchVariable("$retval", "x64", ProbeTypeKRetProbe))
+	return f
+}
+
+// FuncParamValue attaches a fieldsBuilder to the fetchArg that fetches the function parameter at the given index,
+// starting from zero, through $argN (N = paramIndex + 1) instead of the architecture register. With
+// FetchArgTypeAuto the fetchArg type is inferred from the function prototype, when available in the BTF spec,
+// otherwise it is x64. A negative index results in an ErrUnsupportedFuncParamIndex error.
+//
+// Note that FuncParamValue is compatible only with ProbeTypeKProbe. If combined with any other type of Probe it will
+// return an ErrIncompatibleFetchArg error.
+func (f *fetchArg) FuncParamValue(paramIndex int) *fetchArg {
+	v := newFetchVariable("$arg"+strconv.Itoa(paramIndex+1), "x64", ProbeTypeKProbe)
+	v.paramIndex = paramIndex
+	if paramIndex < 0 {
+		v.err = fmt.Errorf("param index %d: %w", paramIndex, ErrUnsupportedFuncParamIndex)
+	}
+	f.fBuilders = append(f.fBuilders, v)
+	return f
+}
+
+// Stack attaches a fieldsBuilder to the fetchArg that fetches the stack address, namely $stack. With
+// FetchArgTypeAuto the fetchArg type is inferred as x64.
+//
+// Note that Stack is compatible with any type of Probe.
+func (f *fetchArg) Stack() *fetchArg {
+	f.fBuilders = append(f.fBuilders, newFetchVariable("$stack", "x64"))
+	return f
+}
+
+// StackEntry attaches a fieldsBuilder to the fetchArg that fetches the stack entry at the given index, namely
+// $stackN. With FetchArgTypeAuto the fetchArg type is inferred as x64. A negative index results in an
+// ErrInvalidFetchVariable error.
+//
+// Note that StackEntry is compatible with any type of Probe.
+func (f *fetchArg) StackEntry(index int) *fetchArg {
+	v := newFetchVariable("$stack"+strconv.Itoa(index), "x64")
+	if index < 0 {
+		v.err = fmt.Errorf("stack entry %d: %w", index, ErrInvalidFetchVariable)
+	}
+	f.fBuilders = append(f.fBuilders, v)
+	return f
+}
+
+// Immediate attaches a fieldsBuilder to the fetchArg that fetches the given immediate value, namely \value. With
+// FetchArgTypeAuto the fetchArg type is inferred as s64.
+//
+// Note that Immediate is compatible with any type of Probe.
+func (f *fetchArg) Immediate(value int64) *fetchArg {
+	f.fBuilders = append(f.fBuilders, newFetchVariable(`\`+strconv.FormatInt(value, 10), "s64"))
+	return f
+}
+
+// ImmediateString attaches a fieldsBuilder to the fetchArg that fetches the given immediate string, namely
+// \"value", which requires the string fetchArg type. With FetchArgTypeAuto the fetchArg type is inferred as string.
+// Since the tracing fs splits the fetchArgs on whitespaces, a value that contains whitespaces or double quotes
+// results in an ErrInvalidFetchVariable error.
+//
+// Note that ImmediateString is compatible with any type of Probe.
+func (f *fetchArg) ImmediateString(value string) *fetchArg {
+	v := newFetchVariable(`\"`+value+`"`, "string")
+	v.inPlace = true
+	if strings.ContainsAny(value, "\" \t\n\r\v\f") {
+		v.err = fmt.Errorf("immediate string %q: %w", value, ErrInvalidFetchVariable)
+	}
+	f.fBuilders = append(f.fBuilders, v)
+	return f
+}
+
 // build iterates all attached fieldBuilders to the fetchArg until the first that builds successfully. Then based on it,
 // it builds the respective tracing fs representation of the fetchArg. If there are no attached fieldBuilders it returns
 // an ErrMissingFieldBuilders error. If no builder builds successfully it returns all the errors that occurred during
 // build.
-func (f *fetchArg) build(spec btfSpec, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+func (f *fetchArg) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
 	var allErr error
 
 	// missing fieldBuilders
@@ -170,29 +373,89 @@ func (f *fetchArg) build(spec btfSpec, probeType ProbeType, funcType *btf.Func,
 	}
 
 	f.btfFunc = funcType
+	f.successfulBuilder = nil
+	f.info = nil
 
 	// iterate all attached fieldBuilders
-	for _, p := range f.fBuilders {
-		paramTracingStr, err := p.build(spec, probeType, funcType, regs)
+	for i, p := range f.fBuilders {
+		// fields resolved by a previous build don't denote where the resolution stops in this one
+		for _, fld := range p.getFields() {
+			fld.seen = false
+		}
+
+		paramTracingStr, err := p.build(spec, opts, probeType, funcType, regs)
 		if err != nil {
 			// in case of error continue to the next fieldsBuilder
-			allErr = errors.Join(allErr, err)
+			allErr = errors.Join(allErr, newResolutionError(f.name, i, p, err))
 			continue
 		}
 
+		argType := f.argType
+		if leafBitfield := leafFieldBitfield(p.getFields()); leafBitfield != nil {
+			// fields that resolve to a bitfield member can only be fetched with a bitfield type, which is inferred
+			// from the member layout unless one is given explicitly
+			switch {
+			case argType == FetchArgTypeAuto || argType == "":
+				argType = leafBitfield.fetchArgType()
+			case !isBitfieldFetchArgType(argType):
+				allErr = errors.Join(allErr, fmt.Errorf("%s of bitfield member: %w", argType, ErrInvalidBitfieldFetchArgType))
+				continue
+			}
+		} else if v, ok := p.(*fetchVariable); ok && argType == FetchArgTypeAuto {
+			argType, err = v.inferFetchArgType()
+			if err != nil {
+				allErr = errors.Join(allErr, err)
+				continue
+			}
+		} else if argType == FetchArgTypeAuto {
+			argType, err = inferFetchArgType(p.getLeafType())
+			if err != nil {
+				// the type of this fieldsBuilder can't be inferred, continue to the next one
+				allErr = errors.Join(allErr, err)
+				continue
+			}
+		}
+
+		if isArrayFetchArgType(argType) {
+			if err := validateArrayFetchArgType(argType, p.getLeafType()); err != nil {
+				// the array type doesn't fit this fieldsBuilder, continue to the next one
+				allErr = errors.Join(allErr, err)
+				continue
+			}
+		}
+
 		f.successfulBuilder = p
 
+		if argType == "string" && isFuncPointer(p.getLeafType()) {
+			// the string of a function pointer is the name of the function it points to
+			argType = "symstr"
+		}
+
+		derefStr := "+0("
+		if leafInUserSpace(p.getLeafType(), p.getFields()) {
+			// the data reside in user-space memory, thus fetch them with the respective user-space type or dereference
+			if argType == "string" {
+				argType = "ustring"
+			} else {
+				derefStr = "+u0("
+			}
+		}
+
+		f.info = newFetchArgInfo(f.name, i, p, argType, probeType, regs)
+
 		fetchArgTracingStr := strings.Builder{}
 		fetchArgTracingStr.WriteString(f.name)
 		fetchArgTracingStr.WriteString("=")
-		if f.argType == "string" {
-			fetchArgTracingStr.WriteString("+0(")
+		if fetchesFromAddress(argType) && !leafFieldIsArray(p.getFields()) && !fetchesInPlace(p) {
+			// the value of the fields is a pointer, thus dereference it to fetch the data it points to
+			fetchArgTracingStr.WriteString(derefStr)
 			fetchArgTracingStr.WriteString(paramTracingStr)
-			fetchArgTracingStr.WriteString("):string")
+			fetchArgTracingStr.WriteString("):")
+			fetchArgTracingStr.WriteString(argType)
 		} else {
 			fetchArgTracingStr.WriteString(paramTracingStr)
 			fetchArgTracingStr.WriteString(":")
-			fetchArgTracingStr.WriteString(f.argType)
+			fetchArgTracingStr.WriteString(argType)
 		}
 
 		return fetchArgTracingStr.String(), nil
@@ -200,3 +463,21 @@ func (f *fetchArg) build(spec btfSpec, probeType ProbeType, funcType *btf.Func,
 
 	return "", allErr
 }
+
+// fetchesFromAddress returns true if the given fetchArg type fetches the data from a memory address, namely
+// string, ustring and array types (e.g. u8[16]), instead of the value itself.
+func fetchesFromAddress(argType string) bool {
+	return argType == "string" || argType == "ustring" || isArrayFetchArgType(argType)
+}
+
+// fetchesInPlace returns true if the given fieldsBuilder resolves to a value that the tracing fs fetches as is,
+// regardless of the fetchArg type, e.g. $comm.
+func fetchesInPlace(p fieldsBuilder) bool {
+	v, ok := p.(*fetchVariable)
+	return ok && v.inPlace
+}
+
+// isArrayFetchArgType returns true if the given fetchArg type is an array type, e.g. u32[4] or char[16].
+func isArrayFetchArgType(argType string) bool {
+	return strings.HasSuffix(argType, "]") && strings.Contains(argType, "[")
+}
@@ -0,0 +1,152 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// maxArrayFetchArgLen is the maximum number of elements that the tracing fs supports for array fetchArg types.
+const maxArrayFetchArgLen = 64
+
+// NewArrayFetchArgs creates and returns count fetchArgs, one per array element, with the given type and the
+// element index appended to the given name (e.g. nr0, nr1, ...). The attach function is called for every fetchArg
+// with the respective array index field (e.g. "index:0") and is responsible to attach the fieldsBuilders to it,
+// placing the index field after the array field. This is useful to fetch the elements of an array of pointers
+// (or of structs), e.g. pid->numbers[0..level].nr, which can't be fetched with an array fetchArg type. On the
+// contrary, array fetchArg types, e.g. u32[4], only target btf arrays, against which they are validated, or pointers.
+func NewArrayFetchArgs(argName string, argType string, count int, attach func(f *fetchArg, index string) *fetchArg) []*fetchArg {
+	fetchArgs := make([]*fetchArg, 0, count)
+
+	for i := 0; i < count; i++ {
+		arg := NewFetchArg(fmt.Sprintf("%s%d", argName, i), argType)
+		fetchArgs = append(fetchArgs, attach(arg, fmt.Sprintf("index:%d", i)))
+	}
+
+	return fetchArgs
+}
+
+// parseArrayFetchArgType parses an array fetchArg type, e.g. u32[4], and returns the element type and the number
+// of elements.
+func parseArrayFetchArgType(argType string) (string, uint32, error) {
+	openIdx := strings.Index(argType, "[")
+	if openIdx <= 0 || !strings.HasSuffix(argType, "]") {
+		return "", 0, fmt.Errorf("array type %s invalid format: %w", argType, ErrInvalidArrayFetchArgType)
+	}
+
+	elemType := argType[:openIdx]
+	nelems, err := strconv.ParseUint(argType[openIdx+1:len(argType)-1], 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("array type %s invalid number of elements: %w", argType, ErrInvalidArrayFetchArgType)
+	}
+
+	if nelems == 0 || nelems > maxArrayFetchArgLen {
+		return "", 0, fmt.Errorf("array type %s number of elements not in [1, %d]: %w", argType, maxArrayFetchArgLen, ErrInvalidArrayFetchArgType)
+	}
+
+	return elemType, uint32(nelems), nil
+}
+
+// validateArrayFetchArgType validates the given array fetchArg type against the btf type that the fields resolve to.
+// For a btf array, the number of elements must not exceed the ones of the btf array and the element type must fit
+// the btf array elements, namely integer types must match their size, char the char elements, string and ustring
+// the pointers to char and symbol and symstr the function pointers. For a pointer, there is nothing to validate
+// against, so only the format of the array fetchArg type is checked. Any other btf type, e.g. an int or a struct,
+// results in an ErrInvalidArrayFetchArgType error, since its value would be dereferenced as an address.
+func validateArrayFetchArgType(argType string, leafType btf.Type) error {
+	elemType, nelems, err := parseArrayFetchArgType(argType)
+	if err != nil {
+		return err
+	}
+
+	var arrayType *btf.Array
+	switch t := btf.UnderlyingType(leafType).(type) {
+	case *btf.Pointer:
+		return nil
+	case *btf.Array:
+		arrayType = t
+	default:
+		return fmt.Errorf("array type %s of non array btf type %T: %w", argType, t, ErrInvalidArrayFetchArgType)
+	}
+
+	if nelems > arrayType.Nelems {
+		return fmt.Errorf("array type %s exceeds the %d elements of the btf array: %w", argType, arrayType.Nelems, ErrInvalidArrayFetchArgType)
+	}
+
+	if !arrayElemTypeFits(elemType, arrayType.Type) {
+		return fmt.Errorf("array type %s element type doesn't fit the btf array elements of type %s: %w", argType,
+			cTypeName(arrayType.Type, false), ErrInvalidArrayFetchArgType)
+	}
+
+	return nil
+}
+
+// arrayElemTypeFits returns true if the given element fetchArg type can fetch the given btf array element type.
+func arrayElemTypeFits(elemType string, btfElemType btf.Type) bool {
+	switch elemType {
+	case "char":
+		return isCharType(btfElemType)
+	case "string", "ustring":
+		ptr, ok := btf.UnderlyingType(btfElemType).(*btf.Pointer)
+		return ok && isCharType(ptr.Target)
+	case "symbol", "symstr":
+		return isFuncPointer(btfElemType)
+	}
+
+	typeSizeBytes, ok := integerFetchArgTypeSize(elemType)
+	if !ok {
+		return false
+	}
+
+	switch btf.UnderlyingType(btfElemType).(type) {
+	case *btf.Int, *btf.Enum, *btf.Pointer:
+		return typeSizeBytes == getArrayTypeSizeBytes(btfElemType)
+	default:
+		return false
+	}
+}
+
+// integerFetchArgTypeSize returns the size in bytes of the given integer fetchArg type, e.g. 4 for u32, s32 and x32.
+func integerFetchArgTypeSize(argType string) (uint32, bool) {
+	if len(argType) < 2 {
+		return 0, false
+	}
+
+	switch argType[0] {
+	case 'u', 's', 'x':
+	default:
+		return 0, false
+	}
+
+	switch argType[1:] {
+	case "8":
+		return 1, true
+	case "16":
+		return 2, true
+	case "32":
+		return 4, true
+	case "64":
+		return 8, true
+	default:
+		return 0, false
+	}
+}
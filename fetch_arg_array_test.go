@@ -0,0 +1,174 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateArrayFetchArgType(t *testing.T) {
+	u32Array := &btf.Array{
+		Type: &btf.Int{
+			Name: "unsigned int",
+			Size: 4,
+		},
+		Nelems: 4,
+	}
+	charType := &btf.Int{Name: "char", Size: 1, Encoding: btf.Char}
+	charArray := &btf.Array{Type: charType, Nelems: 16}
+	stringArray := &btf.Array{Type: &btf.Pointer{Target: &btf.Const{Type: charType}}, Nelems: 2}
+	funcPtrArray := &btf.Array{Type: &btf.Pointer{Target: &btf.FuncProto{Return: &btf.Void{}}}, Nelems: 2}
+
+	cases := []struct {
+		name     string
+		argType  string
+		leafType btf.Type
+		err      error
+	}{
+		{
+			name:     "all_elements",
+			argType:  "u32[4]",
+			leafType: u32Array,
+		},
+		{
+			name:     "less_elements",
+			argType:  "x32[2]",
+			leafType: u32Array,
+		},
+		{
+			name:     "typedef_array",
+			argType:  "s32[3]",
+			leafType: &btf.Typedef{Name: "u32_array", Type: u32Array},
+		},
+		{
+			name:     "char_elements",
+			argType:  "char[16]",
+			leafType: charArray,
+		},
+		{
+			name:     "string_elements",
+			argType:  "string[2]",
+			leafType: stringArray,
+		},
+		{
+			name:     "symbol_elements",
+			argType:  "symbol[2]",
+			leafType: funcPtrArray,
+		},
+		{
+			name:     "string_elements_mismatch",
+			argType:  "string[4]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "char_elements_mismatch",
+			argType:  "char[4]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "integer_elements_mismatch",
+			argType:  "u32[2]",
+			leafType: &btf.Array{Type: &btf.Struct{Name: "kuid_t", Size: 4}, Nelems: 2},
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "scalar_leaf",
+			argType:  "u32[4]",
+			leafType: &btf.Int{Name: "unsigned int", Size: 4},
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "enum_leaf",
+			argType:  "u32[4]",
+			leafType: &btf.Enum{Name: "pid_type", Size: 4},
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "struct_leaf",
+			argType:  "u32[4]",
+			leafType: &btf.Struct{Name: "qstr", Size: 16},
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "more_elements",
+			argType:  "u32[5]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "element_size_mismatch",
+			argType:  "u64[2]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "pointer_not_validated",
+			argType:  "u8[16]",
+			leafType: &btf.Pointer{Target: &btf.Void{}},
+		},
+		{
+			name:     "zero_elements",
+			argType:  "u32[0]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "exceeds_max_elements",
+			argType:  "u8[65]",
+			leafType: &btf.Pointer{Target: &btf.Void{}},
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "invalid_number_of_elements",
+			argType:  "u32[four]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:     "missing_element_type",
+			argType:  "[4]",
+			leafType: u32Array,
+			err:      ErrInvalidArrayFetchArgType,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateArrayFetchArgType(c.argType, c.leafType)
+			require.ErrorIs(t, err, c.err)
+		})
+	}
+}
+
+func TestNewArrayFetchArgs(t *testing.T) {
+	fetchArgs := NewArrayFetchArgs("nr", "u32", 3, func(f *fetchArg, index string) *fetchArg {
+		return f.FuncParamWithName("tsk_param", "", "numbers", index, "val")
+	})
+	require.Len(t, fetchArgs, 3)
+
+	for i, name := range []string{"nr0", "nr1", "nr2"} {
+		require.Equal(t, name, fetchArgs[i].name)
+		require.Equal(t, "u32", fetchArgs[i].argType)
+		require.Len(t, fetchArgs[i].fBuilders, 1)
+	}
+}
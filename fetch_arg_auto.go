@@ -0,0 +1,145 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// FetchArgTypeAuto is a fetchArg type that instructs the build to infer the actual fetchArg type from the btf type
+// that the fields resolve to. Specifically, btf ints and enums map to the respective signed or unsigned type of the
+// same size (e.g. s32, u64), pointers to char and embedded char arrays map to string, function pointers map to
+// symbol, any other pointer maps to a hex type of the pointer size (x64), and any other embedded array maps to the
+// respective array type of all its elements (e.g. u32[4]). As a result, the inferred type may differ between kernels
+// while the fetchArg definition stays the same. When the type cannot be inferred, ErrUnsupportedAutoType is
+// returned. Note that fields that resolve to a function pointer, e.g. file->f_op->read_iter, are symbolized even
+// with an explicit type, in which case the string type is replaced by symstr.
+const FetchArgTypeAuto = "auto"
+
+// pointerSizeBytes is the size of a pointer for all the supported architectures. Auto inference is thus 64-bit only,
+// which holds as long as getRegistersResolver supports only 64-bit architectures, since btf doesn't record the
+// pointer size (btf.Sizeof assumes 8 bytes as well).
+const pointerSizeBytes = 8
+
+// inferFetchArgType returns the tracing fs fetchArg type that corresponds to the given btf type.
+func inferFetchArgType(btfType btf.Type) (string, error) {
+	if btfType == nil {
+		return "", fmt.Errorf("missing btf type: %w", ErrUnsupportedAutoType)
+	}
+
+	switch t := btf.UnderlyingType(btfType).(type) {
+	case *btf.Int:
+		if t.Encoding == btf.Bool {
+			return integerFetchArgType(t.Size, false)
+		}
+		return integerFetchArgType(t.Size, t.Encoding == btf.Signed)
+	case *btf.Enum:
+		return integerFetchArgType(t.Size, t.Signed)
+	case *btf.Pointer:
+		if isCharType(t.Target) {
+			return "string", nil
+		}
+		if isFuncPointer(t) {
+			return "symbol", nil
+		}
+		return fmt.Sprintf("x%d", pointerSizeBytes*8), nil
+	case *btf.Array:
+		if isCharType(t.Type) {
+			return "string", nil
+		}
+
+		if t.Nelems == 0 || t.Nelems > maxArrayFetchArgLen {
+			return "", fmt.Errorf("array of %d elements: %w", t.Nelems, ErrUnsupportedAutoType)
+		}
+
+		elemType, err := inferFetchArgType(t.Type)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s[%d]", elemType, t.Nelems), nil
+	default:
+		return "", fmt.Errorf("btf type %s of kind %T: %w", btfType.TypeName(), t, ErrUnsupportedAutoType)
+	}
+}
+
+// isFuncPointer returns true if the given btf type is a pointer to a function prototype, e.g. the members of
+// struct file_operations.
+func isFuncPointer(btfType btf.Type) bool {
+	if btfType == nil {
+		return false
+	}
+
+	ptr, ok := btf.UnderlyingType(btfType).(*btf.Pointer)
+	if !ok {
+		return false
+	}
+
+	_, ok = btf.UnderlyingType(ptr.Target).(*btf.FuncProto)
+	return ok
+}
+
+// integerFetchArgType returns the signed or unsigned tracing fs fetchArg type of the given size in bytes.
+func integerFetchArgType(sizeBytes uint32, signed bool) (string, error) {
+	switch sizeBytes {
+	case 1, 2, 4, 8:
+	default:
+		return "", fmt.Errorf("integer of size %d bytes: %w", sizeBytes, ErrUnsupportedAutoType)
+	}
+
+	if signed {
+		return fmt.Sprintf("s%d", sizeBytes*8), nil
+	}
+
+	return fmt.Sprintf("u%d", sizeBytes*8), nil
+}
+
+// isCharType returns true if the given btf type, after skipping any qualifiers, is a char. Note that typedefs are
+// not skipped on purpose, so byte buffers such as u8 are not considered strings.
+func isCharType(btfType btf.Type) bool {
+	intType, ok := skipQualifiers(btfType).(*btf.Int)
+	if !ok || intType.Size != 1 {
+		return false
+	}
+
+	switch intType.Name {
+	case "char", "signed char", "unsigned char":
+		return true
+	default:
+		return intType.Encoding == btf.Char
+	}
+}
+
+// skipQualifiers returns the given btf type without any const, volatile and restrict qualifiers and type tags.
+func skipQualifiers(btfType btf.Type) btf.Type {
+	for {
+		switch t := btfType.(type) {
+		case *btf.TypeTag:
+			btfType = t.Type
+		case *btf.Const:
+			btfType = t.Type
+		case *btf.Volatile:
+			btfType = t.Type
+		case *btf.Restrict:
+			btfType = t.Type
+		default:
+			return btfType
+		}
+	}
+}
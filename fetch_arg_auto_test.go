@@ -0,0 +1,265 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_inferFetchArgType(t *testing.T) {
+	charType := &btf.Int{
+		Name:     "char",
+		Size:     1,
+		Encoding: btf.Signed,
+	}
+
+	cases := []struct {
+		name         string
+		btfType      btf.Type
+		expectedType string
+		err          error
+	}{
+		{
+			name: "unsigned_int",
+			btfType: &btf.Int{
+				Name: "unsigned int",
+				Size: 4,
+			},
+			expectedType: "u32",
+		},
+		{
+			name: "signed_int",
+			btfType: &btf.Int{
+				Name:     "int",
+				Size:     4,
+				Encoding: btf.Signed,
+			},
+			expectedType: "s32",
+		},
+		{
+			name: "bool",
+			btfType: &btf.Int{
+				Name:     "_Bool",
+				Size:     1,
+				Encoding: btf.Bool,
+			},
+			expectedType: "u8",
+		},
+		{
+			name: "typedef_int",
+			btfType: &btf.Typedef{
+				Name: "u64",
+				Type: &btf.Int{
+					Name: "long long unsigned int",
+					Size: 8,
+				},
+			},
+			expectedType: "u64",
+		},
+		{
+			name: "const_int",
+			btfType: &btf.Const{
+				Type: &btf.Int{
+					Name:     "short int",
+					Size:     2,
+					Encoding: btf.Signed,
+				},
+			},
+			expectedType: "s16",
+		},
+		{
+			name: "int128",
+			btfType: &btf.Int{
+				Name: "__int128 unsigned",
+				Size: 16,
+			},
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name: "unsigned_enum",
+			btfType: &btf.Enum{
+				Name: "pid_type",
+				Size: 4,
+			},
+			expectedType: "u32",
+		},
+		{
+			name: "signed_enum",
+			btfType: &btf.Enum{
+				Name:   "an_enum",
+				Size:   8,
+				Signed: true,
+			},
+			expectedType: "s64",
+		},
+		{
+			name: "char_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Const{
+					Type: charType,
+				},
+			},
+			expectedType: "string",
+		},
+		{
+			name: "struct_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Struct{
+					Name: "inode",
+				},
+			},
+			expectedType: "x64",
+		},
+		{
+			name: "void_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Void{},
+			},
+			expectedType: "x64",
+		},
+		{
+			name: "func_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.FuncProto{
+					Return: &btf.Void{},
+				},
+			},
+			expectedType: "symbol",
+		},
+		{
+			name: "typedef_func_pointer",
+			btfType: &btf.Typedef{
+				Name: "filldir_t",
+				Type: &btf.Pointer{
+					Target: &btf.Const{
+						Type: &btf.FuncProto{
+							Return: &btf.Void{},
+						},
+					},
+				},
+			},
+			expectedType: "symbol",
+		},
+		{
+			name: "char_array",
+			btfType: &btf.Array{
+				Type:   charType,
+				Nelems: 16,
+			},
+			expectedType: "string",
+		},
+		{
+			name: "unsigned_char_array",
+			btfType: &btf.Array{
+				Type: &btf.Int{
+					Name: "unsigned char",
+					Size: 1,
+				},
+				Nelems: 32,
+			},
+			expectedType: "string",
+		},
+		{
+			name: "int_array",
+			btfType: &btf.Array{
+				Type: &btf.Int{
+					Name:     "int",
+					Size:     4,
+					Encoding: btf.Signed,
+				},
+				Nelems: 4,
+			},
+			expectedType: "s32[4]",
+		},
+		{
+			name: "char_pointer_array",
+			btfType: &btf.Array{
+				Type: &btf.Pointer{
+					Target: charType,
+				},
+				Nelems: 2,
+			},
+			expectedType: "string[2]",
+		},
+		{
+			name: "struct_array",
+			btfType: &btf.Array{
+				Type: &btf.Struct{
+					Name: "upid",
+				},
+				Nelems: 1,
+			},
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name: "large_array",
+			btfType: &btf.Array{
+				Type: &btf.Int{
+					Name: "unsigned int",
+					Size: 4,
+				},
+				Nelems: 128,
+			},
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name: "u8_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Typedef{
+					Name: "u8",
+					Type: &btf.Int{
+						Name: "unsigned char",
+						Size: 1,
+					},
+				},
+			},
+			expectedType: "x64",
+		},
+		{
+			name: "struct",
+			btfType: &btf.Struct{
+				Name: "inode",
+			},
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name: "float",
+			btfType: &btf.Float{
+				Name: "double",
+				Size: 8,
+			},
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name:    "nil",
+			btfType: nil,
+			err:     ErrUnsupportedAutoType,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			argType, err := inferFetchArgType(c.btfType)
+			require.ErrorIs(t, err, c.err)
+			require.Equal(t, c.expectedType, argType)
+		})
+	}
+}
@@ -20,8 +20,60 @@ package tkbtf
 import (
 	"fmt"
 	"math/bits"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
 )
 
+// bitfield describes the layout of a bitfield member inside the smallest naturally aligned container
+// that is able to hold it.
+type bitfield struct {
+	sizeBits      uint32
+	offsetBits    uint32
+	containerBits uint32
+}
+
+// bitfieldFromMember calculates the layout of the given bitfield member. The container size starts from the size
+// of the member type and, if the bitfield straddles the boundaries of the naturally aligned container, it grows up
+// to 64 bits. It returns the offset in bytes of the container and the bitfield layout in respect to the container.
+// If no container can hold the bitfield, it returns an ErrUnsupportedBitfield error.
+func bitfieldFromMember(m btf.Member) (uint32, *bitfield, error) {
+	sizeBits := uint32(m.BitfieldSize)
+	offsetBits := uint32(m.Offset)
+
+	containerBits := getArrayTypeSizeBytes(m.Type) * 8
+	if containerBits < 8 {
+		containerBits = 8
+	}
+
+	for ; containerBits <= 64; containerBits *= 2 {
+		containerOffsetBits := offsetBits - offsetBits%containerBits
+		bitOffset := offsetBits - containerOffsetBits
+		if bitOffset+sizeBits > containerBits {
+			continue
+		}
+
+		return containerOffsetBits / 8, &bitfield{
+			sizeBits:      sizeBits,
+			offsetBits:    bitOffset,
+			containerBits: containerBits,
+		}, nil
+	}
+
+	return 0, nil, fmt.Errorf("bitfield %s of %d bits at bit offset %d: %w", m.Name, sizeBits, offsetBits, ErrUnsupportedBitfield)
+}
+
+// fetchArgType returns the string representation of the bitfield fetchArg type
+// (https://docs.kernel.org/trace/kprobetrace.html#types).
+func (b *bitfield) fetchArgType() string {
+	return fmt.Sprintf("b%d@%d/%d", b.sizeBits, b.offsetBits, b.containerBits)
+}
+
+// isBitfieldFetchArgType returns true if the given fetchArg type is a bitfield type, e.g. b3@5/32.
+func isBitfieldFetchArgType(argType string) bool {
+	return strings.HasPrefix(argType, "b") && strings.Contains(argType, "@")
+}
+
 // BitFieldTypeMask generates the string representation of a bitfield fetchArg type based on the given mask value
 // (https://docs.kernel.org/trace/kprobetrace.html#types). Specifically, it dynamically determines the leading zeros,
 // ones count, and size of container based on the mask (supported types are uint8, uint16, uint32, or uint64) and performs
@@ -29,6 +81,9 @@ import (
 // is assigned to a fetchArg, it causes only the bits of the fetch arg value that fall withing the original mask
 // to remain (ret := fetchArgValue & mask) and then be shifted all the way to the right (ret := ret >> maskTrailingZeros).
 // The bitfield type has a format of "b{bitWidth}@{bitOffset}/{containerSize}".
+// Fields that resolve to a btf bitfield member don't need it, since with FetchArgTypeAuto they get the respective
+// bitfield type, e.g. b3@5/32, while any other type that is not a bitfield one results in an
+// ErrInvalidBitfieldFetchArgType error.
 //
 // Note: masks without consecutive ones (e.g. 0x5) are not supported and their behavior is undefined.
 func BitFieldTypeMask[T interface {
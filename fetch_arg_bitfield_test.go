@@ -20,6 +20,7 @@ package tkbtf
 import (
 	"testing"
 
+	"github.com/cilium/ebpf/btf"
 	"github.com/stretchr/testify/require"
 )
 
@@ -40,3 +41,94 @@ func TestBitFieldTypeMask(t *testing.T) {
 	bitFieldType = BitFieldTypeMask(uint32(0b111100000))
 	require.Equal(t, "b4@5/32", bitFieldType)
 }
+
+func Test_bitfieldFromMember(t *testing.T) {
+	typeUint8 := &btf.Int{
+		Name: "unsigned char",
+		Size: 1,
+	}
+
+	typeUint32 := &btf.Int{
+		Name: "unsigned int",
+		Size: 4,
+	}
+
+	cases := []struct {
+		name                 string
+		member               btf.Member
+		expectedOffsetBytes  uint32
+		expectedFetchArgType string
+		err                  error
+	}{
+		{
+			name: "aligned_container",
+			member: btf.Member{
+				Name:         "sk_protocol",
+				Type:         typeUint32,
+				Offset:       520,
+				BitfieldSize: 8,
+			},
+			expectedOffsetBytes:  64,
+			expectedFetchArgType: "b8@8/32",
+		},
+		{
+			name: "first_bit",
+			member: btf.Member{
+				Name:         "flag",
+				Type:         typeUint8,
+				Offset:       16,
+				BitfieldSize: 1,
+			},
+			expectedOffsetBytes:  2,
+			expectedFetchArgType: "b1@0/8",
+		},
+		{
+			name: "straddling_container",
+			member: btf.Member{
+				Name:         "straddling",
+				Type:         typeUint8,
+				Offset:       6,
+				BitfieldSize: 4,
+			},
+			expectedOffsetBytes:  0,
+			expectedFetchArgType: "b4@6/16",
+		},
+		{
+			name: "typedef_container",
+			member: btf.Member{
+				Name: "typedef_bits",
+				Type: &btf.Typedef{
+					Name: "u32",
+					Type: typeUint32,
+				},
+				Offset:       70,
+				BitfieldSize: 2,
+			},
+			expectedOffsetBytes:  8,
+			expectedFetchArgType: "b2@6/32",
+		},
+		{
+			name: "unsupported_container",
+			member: btf.Member{
+				Name:         "unsupported",
+				Type:         typeUint32,
+				Offset:       60,
+				BitfieldSize: 8,
+			},
+			err: ErrUnsupportedBitfield,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			offsetBytes, bf, err := bitfieldFromMember(c.member)
+			require.ErrorIs(t, err, c.err)
+			if c.err != nil {
+				return
+			}
+
+			require.Equal(t, c.expectedOffsetBytes, offsetBytes)
+			require.Equal(t, c.expectedFetchArgType, bf.fetchArgType())
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"github.com/cilium/ebpf/btf"
+)
+
+// FetchArgInfo describes how a fetch arg was resolved during the last build of its Probe. This is useful for
+// debugging kernel-specific breakage, e.g. which of the fallback fieldsBuilders matched and which offsets and btf
+// types were chosen.
+type FetchArgInfo struct {
+	// Name is the name of the fetch arg.
+	Name string
+	// BuilderIndex is the index, in respect to the order they were added, of the fieldsBuilder that built
+	// successfully.
+	BuilderIndex int
+	// ParamIndex is the index of the function parameter that the fetch arg is fetched from, or -1 when the fetch
+	// arg is not fetched from a function parameter.
+	ParamIndex int
+	// Root is where fetching starts, namely the register, the global variable symbol, e.g. @jiffies, or the tracing
+	// fs variable, e.g. $comm.
+	Root string
+	// Fields holds the resolved fields in the order they are traversed.
+	Fields []FieldInfo
+	// Type is the final kprobe fetch arg type, e.g. u32, string or b3@5/32.
+	Type string
+}
+
+// FieldInfo describes a resolved field of a fetch arg.
+type FieldInfo struct {
+	// Name is the field name as given to the fieldsBuilder.
+	Name string
+	// MemberName is the name of the member that the field resolved to, when it differs from Name, e.g. an alias.
+	MemberName string
+	// Offset is the offset in bytes of the field from the address it is read from, namely the root or the target
+	// of the last pointer dereference. Thus, the offset of a member of an embedded struct includes the offset of
+	// the latter, e.g. the name of the d_name of a dentry is at 40 when d_name is at 32.
+	Offset int64
+	// TypeName is the C declaration of the btf type of the field, e.g. struct inode *.
+	TypeName string
+	// SizeBytes is the size in bytes of the btf type of the field.
+	SizeBytes uint32
+}
+
+// newFetchArgInfo returns the FetchArgInfo of the fieldsBuilder at the given index, that built successfully with the
+// given fetch arg type.
+func newFetchArgInfo(name string, builderIndex int, p fieldsBuilder, argType string, probeType ProbeType, regs registersResolver) *FetchArgInfo {
+	info := &FetchArgInfo{
+		Name:         name,
+		BuilderIndex: builderIndex,
+		ParamIndex:   -1,
+		Type:         argType,
+	}
+
+	switch t := p.(type) {
+	case *funcParamWithName:
+		info.ParamIndex = t.foundIndex
+	case *funcParamWithType:
+		info.ParamIndex = t.foundIndex
+	case *funcParamArbitrary:
+		info.ParamIndex = t.index
+	case *funcParamAtIndex:
+		info.ParamIndex = t.index
+	case *globalVariable:
+		info.Root = "@" + t.name
+	case *fetchVariable:
+		info.ParamIndex = t.paramIndex
+		info.Root = t.variable
+	}
+
+	if info.Root == "" {
+		switch probeType {
+		case ProbeTypeKRetProbe:
+			info.Root = regs.GetFuncReturnRegister()
+		case ProbeTypeKProbe:
+			// the register was already resolved during build, thus it can't fail here
+			info.Root, _ = regs.GetFuncParamRegister(info.ParamIndex)
+		}
+	}
+
+	for _, fld := range p.getFields() {
+		fieldInfo := FieldInfo{
+			Name:       fld.name,
+			MemberName: fld.memberName,
+			Offset:     fld.offset,
+			TypeName:   cTypeName(fld.valueBtfType, false),
+		}
+
+		if size, err := btf.Sizeof(fld.valueBtfType); err == nil {
+			fieldInfo.SizeBytes = uint32(size)
+		}
+
+		info.Fields = append(info.Fields, fieldInfo)
+	}
+
+	return info
+}
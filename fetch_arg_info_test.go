@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe_GetFetchArgsInfo(t *testing.T) {
+	spec := generateBTFSpec()
+
+	probe := NewKProbe().AddFetchArgs(
+		NewFetchArg("fi", "u32").
+			FuncParamWithName("unknown_param", "i_ino").
+			FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+		NewFetchArg("comm", "string").Comm(),
+		NewFetchArg("jf", FetchArgTypeAuto).GlobalVariable("jiffies"),
+		NewFetchArg("opt", "u32").FuncParamWithName("unknown_param").SetOptional(true),
+		NewFetchArg("dn", "string").FuncParamWithName("dentry_param", "d_name", "name"),
+	)
+
+	symbol := NewSymbol("test_function").AddProbes(probe)
+	require.NoError(t, spec.BuildSymbol(symbol))
+
+	require.Equal(t, []*FetchArgInfo{
+		{
+			Name:         "fi",
+			BuilderIndex: 1,
+			ParamIndex:   0,
+			Root:         "%di",
+			Fields: []FieldInfo{
+				{Name: "d_inode", Offset: 48, TypeName: "struct inode *", SizeBytes: 8},
+				{Name: "i_ino", Offset: 64, TypeName: "int", SizeBytes: 8},
+			},
+			Type: "u32",
+		},
+		{
+			Name:         "comm",
+			BuilderIndex: 0,
+			ParamIndex:   -1,
+			Root:         "$comm",
+			Type:         "string",
+		},
+		{
+			Name:         "jf",
+			BuilderIndex: 0,
+			ParamIndex:   -1,
+			Root:         "@jiffies",
+			Fields: []FieldInfo{
+				{Name: "jiffies", TypeName: "long unsigned int", SizeBytes: 8},
+			},
+			Type: "u64",
+		},
+		{
+			Name:         "dn",
+			BuilderIndex: 0,
+			ParamIndex:   0,
+			Root:         "%di",
+			Fields: []FieldInfo{
+				{Name: "d_name", Offset: 32, TypeName: "struct qstr", SizeBytes: 16},
+				{Name: "name", Offset: 40, TypeName: "int8 *", SizeBytes: 8},
+			},
+			Type: "string",
+		},
+	}, probe.GetFetchArgsInfo())
+}
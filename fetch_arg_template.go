@@ -0,0 +1,185 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+// FetchArgsTemplate describes a reusable group of named fetchArgs whose fields are relative to a common root type,
+// e.g. the attributes of a struct inode. The template is instantiated onto one or more base paths that resolve to
+// the root type, see TemplateBase, and generates the respective fetchArgs with all their fallbacks.
+type FetchArgsTemplate struct {
+	args []*templateFetchArg
+}
+
+// templateFetchArg holds the name, the type and the alternative relative fields of a fetchArg of a template.
+type templateFetchArg struct {
+	name     string
+	argType  string
+	fields   [][]string
+	optional bool
+}
+
+// NewFetchArgsTemplate creates and returns a new empty FetchArgsTemplate.
+func NewFetchArgsTemplate() *FetchArgsTemplate {
+	return &FetchArgsTemplate{}
+}
+
+// AddFetchArg adds to the template a fetchArg with the given name and type, see NewFetchArg, and fields that are
+// relative to the root type of the template. If the template already contains a fetchArg with the same name, the
+// fields are added as a fallback of the latter, and the given type is ignored.
+func (t *FetchArgsTemplate) AddFetchArg(argName string, argType string, fields ...string) *FetchArgsTemplate {
+	for _, arg := range t.args {
+		if arg.name == argName {
+			arg.fields = append(arg.fields, fields)
+			return t
+		}
+	}
+
+	t.args = append(t.args, &templateFetchArg{
+		name:    argName,
+		argType: argType,
+		fields:  [][]string{fields},
+	})
+	return t
+}
+
+// AddOptionalFetchArg is the same as AddFetchArg but the generated fetchArg is optional, see fetchArg.SetOptional.
+func (t *FetchArgsTemplate) AddOptionalFetchArg(argName string, argType string, fields ...string) *FetchArgsTemplate {
+	t.AddFetchArg(argName, argType, fields...)
+	for _, arg := range t.args {
+		if arg.name == argName {
+			arg.optional = true
+		}
+	}
+	return t
+}
+
+// Instantiate generates the fetchArgs of the template onto the given base, in the order they were added to the
+// template. The name of every generated fetchArg is the name in the template prefixed with the given prefix, e.g.
+// the prefix "f" and the name "ino" generate the fetchArg "fino". Every base path of the given base, in the order
+// they were added, is combined with every alternative fields of the fetchArg, thus the first combination that builds
+// successfully satisfies the fetchArg.
+func (t *FetchArgsTemplate) Instantiate(prefix string, base *TemplateBase) []*fetchArg {
+	fetchArgs := make([]*fetchArg, 0, len(t.args))
+
+	for _, arg := range t.args {
+		f := NewFetchArg(prefix+arg.name, arg.argType).SetOptional(arg.optional)
+		if base == nil {
+			// without any base path the fetchArg has no fieldsBuilders, thus its build returns an error
+			fetchArgs = append(fetchArgs, f)
+			continue
+		}
+
+		for _, attach := range base.attachers {
+			for _, fields := range arg.fields {
+				attach(f, fields)
+			}
+		}
+		fetchArgs = append(fetchArgs, f)
+	}
+
+	return fetchArgs
+}
+
+// TemplateBase holds the base paths, that resolve to the root type of a FetchArgsTemplate, onto which the template
+// is instantiated. Every base path corresponds to a fieldsBuilder of fetchArg, e.g. FuncParamWithName, whose fields
+// are followed by the relative fields of the template. Multiple base paths act as fallbacks of each other.
+type TemplateBase struct {
+	attachers []func(f *fetchArg, fields []string)
+}
+
+// NewTemplateBase creates and returns a new TemplateBase without any base path.
+func NewTemplateBase() *TemplateBase {
+	return &TemplateBase{}
+}
+
+// baseFields returns the given base fields followed by the given relative fields.
+func baseFields(base []string, fields []string) []string {
+	allFields := make([]string, 0, len(base)+len(fields))
+	allFields = append(allFields, base...)
+	return append(allFields, fields...)
+}
+
+// FuncParamWithName adds a base path that is equivalent to fetchArg.FuncParamWithName with the given parameter name
+// and fields.
+func (b *TemplateBase) FuncParamWithName(paramName string, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncParamWithName(paramName, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncParamWithType adds a base path that is equivalent to fetchArg.FuncParamWithType with the given type name,
+// occurrence and fields.
+func (b *TemplateBase) FuncParamWithType(typeName string, occurrence int, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncParamWithType(typeName, occurrence, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncParamArbitrary adds a base path that is equivalent to fetchArg.FuncParamArbitrary with the given parameter
+// index, wrap and fields.
+func (b *TemplateBase) FuncParamArbitrary(paramIndex int, wrap Wrap, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncParamArbitrary(paramIndex, wrap, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncParamWithCustomType adds a base path that is equivalent to fetchArg.FuncParamWithCustomType with the given
+// parameter name, wrap and fields.
+func (b *TemplateBase) FuncParamWithCustomType(paramName string, wrap Wrap, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncParamWithCustomType(paramName, wrap, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncReturn adds a base path that is equivalent to fetchArg.FuncReturn with the given fields.
+func (b *TemplateBase) FuncReturn(fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncReturn(baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncReturnWithType adds a base path that is equivalent to fetchArg.FuncReturnWithType with the given type name
+// and fields.
+func (b *TemplateBase) FuncReturnWithType(typeName string, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncReturnWithType(typeName, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// FuncReturnArbitrary adds a base path that is equivalent to fetchArg.FuncReturnArbitrary with the given wrap and
+// fields.
+func (b *TemplateBase) FuncReturnArbitrary(wrap Wrap, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.FuncReturnArbitrary(wrap, baseFields(fields, relative)...)
+	})
+	return b
+}
+
+// GlobalVariable adds a base path that is equivalent to fetchArg.GlobalVariable with the given variable name and
+// fields.
+func (b *TemplateBase) GlobalVariable(name string, fields ...string) *TemplateBase {
+	b.attachers = append(b.attachers, func(f *fetchArg, relative []string) {
+		f.GlobalVariable(name, baseFields(fields, relative)...)
+	})
+	return b
+}
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchArgsTemplate_Instantiate(t *testing.T) {
+	template := NewFetchArgsTemplate().
+		AddFetchArg("ino", "u64", "i_ino").
+		AddFetchArg("cts", "u64", "i_ctime", "tv_sec").
+		AddFetchArg("cts", "s64", "i_ctime_sec")
+
+	base := NewTemplateBase().
+		FuncParamWithName("dentry", "d_inode").
+		FuncParamWithCustomType("data", WrapPointer, "inode")
+
+	fetchArgs := template.Instantiate("f", base)
+	require.Len(t, fetchArgs, 2)
+
+	require.Equal(t, "fino", fetchArgs[0].name)
+	require.Equal(t, "u64", fetchArgs[0].argType)
+	require.Equal(t, []fieldsBuilder{
+		&funcParamWithName{
+			foundIndex: -1,
+			name:       "dentry",
+			fields:     paramFieldsFromNames("d_inode", "i_ino"),
+		},
+		&funcParamArbitrary{
+			name: "data",
+			funcParamAtIndex: funcParamAtIndex{
+				fields: paramFieldsFromNames("inode", "i_ino"),
+				wrap:   WrapPointer,
+			},
+		},
+	}, fetchArgs[0].fBuilders)
+
+	// the type of a fallback is ignored and every base path is combined with every fallback
+	require.Equal(t, "fcts", fetchArgs[1].name)
+	require.Equal(t, "u64", fetchArgs[1].argType)
+	require.Equal(t, []fieldsBuilder{
+		&funcParamWithName{
+			foundIndex: -1,
+			name:       "dentry",
+			fields:     paramFieldsFromNames("d_inode", "i_ctime", "tv_sec"),
+		},
+		&funcParamWithName{
+			foundIndex: -1,
+			name:       "dentry",
+			fields:     paramFieldsFromNames("d_inode", "i_ctime_sec"),
+		},
+		&funcParamArbitrary{
+			name: "data",
+			funcParamAtIndex: funcParamAtIndex{
+				fields: paramFieldsFromNames("inode", "i_ctime", "tv_sec"),
+				wrap:   WrapPointer,
+			},
+		},
+		&funcParamArbitrary{
+			name: "data",
+			funcParamAtIndex: funcParamAtIndex{
+				fields: paramFieldsFromNames("inode", "i_ctime_sec"),
+				wrap:   WrapPointer,
+			},
+		},
+	}, fetchArgs[1].fBuilders)
+}
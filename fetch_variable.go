@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// fetchVariable is the implementation of the fieldsBuilder interface for the values that the tracing fs provides
+// without relying on the btf spec, namely $comm, $retval, $argN, $stack, $stackN and immediate values.
+type fetchVariable struct {
+	// variable is the tracing fs representation of the value, e.g. $comm or \1234.
+	variable string
+	// probeTypes are the types of Probe that the variable is compatible with; nil means any type of Probe.
+	probeTypes []ProbeType
+	// autoType is the fetchArg type that FetchArgTypeAuto infers to when there is no btf type to infer it from.
+	autoType string
+	// inPlace is true when the tracing fs fetches the string value of the variable as is, instead of
+	// dereferencing it as an address, e.g. $comm.
+	inPlace bool
+	// paramIndex is the index of the function parameter for $argN, otherwise -1.
+	paramIndex int
+	// err is the error of an invalid variable definition that is returned on build.
+	err     error
+	btfType btf.Type
+}
+
+// newFetchVariable creates and returns a new fetchVariable that is not bound to a function parameter.
+func newFetchVariable(variable string, autoType string, probeTypes ...ProbeType) *fetchVariable {
+	return &fetchVariable{
+		variable:   variable,
+		probeTypes: probeTypes,
+		autoType:   autoType,
+		paramIndex: -1,
+	}
+}
+
+func (p *fetchVariable) build(_ btfSpec, _ resolveOptions, probeType ProbeType, funcType *btf.Func, _ registersResolver) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+
+	if p.probeTypes != nil && !isProbeTypeIn(probeType, p.probeTypes) {
+		return "", fmt.Errorf("%s: %w", p.variable, ErrIncompatibleFetchArg)
+	}
+
+	// the function prototype is optional, when available it is used to infer the fetchArg type of $retval and $argN
+	p.btfType = nil
+	if funcType == nil {
+		// the symbol is not validated, thus there is no function prototype
+		return p.variable, nil
+	}
+
+	if funcProtoType, ok := funcType.Type.(*btf.FuncProto); ok {
+		switch {
+		case p.paramIndex >= 0 && p.paramIndex < len(funcProtoType.Params):
+			p.btfType = funcProtoType.Params[p.paramIndex].Type
+		case p.variable == "$retval":
+			p.btfType = funcProtoType.Return
+		}
+	}
+
+	if _, isVoid := p.btfType.(*btf.Void); isVoid {
+		p.btfType = nil
+	}
+
+	return p.variable, nil
+}
+
+func (p *fetchVariable) getFields() []*field {
+	return nil
+}
+
+func (p *fetchVariable) getWrap() Wrap {
+	return WrapNone
+}
+
+func (p *fetchVariable) getLeafType() btf.Type {
+	return p.btfType
+}
+
+// inferFetchArgType returns the fetchArg type that FetchArgTypeAuto resolves to for the variable.
+func (p *fetchVariable) inferFetchArgType() (string, error) {
+	if p.btfType != nil {
+		return inferFetchArgType(p.btfType)
+	}
+
+	if p.autoType == "" {
+		return "", fmt.Errorf("%s: %w", p.variable, ErrUnsupportedAutoType)
+	}
+
+	return p.autoType, nil
+}
+
+// isProbeTypeIn returns true if the given probeType is one of probeTypes.
+func isProbeTypeIn(probeType ProbeType, probeTypes []ProbeType) bool {
+	for _, t := range probeTypes {
+		if t == probeType {
+			return true
+		}
+	}
+	return false
+}
@@ -26,13 +26,30 @@ import (
 	"github.com/cilium/ebpf/btf"
 )
 
+// userSpaceTypeTag is the value of the btf type tag that the kernel attaches to types annotated with __user.
+const userSpaceTypeTag = "user"
+
 type field struct {
 	name            string
-	offset          uint32
+	offset          int64
 	seen            bool
 	includeInOffset bool
 	parentBtfType   btf.Type
 	btfType         btf.Type
+	valueBtfType    btf.Type
+	bitfield        *bitfield
+	userSpace       bool
+	// memberName is the name of the member that the field resolved to, when it differs from the field name,
+	// e.g. the member of a container_of field
+	memberName string
+	// anonymousMembers holds the anonymous members that were traversed to reach the field
+	anonymousMembers []anonymousMember
+}
+
+// anonymousMember describes an anonymous struct or union member of a parent type.
+type anonymousMember struct {
+	parent btf.Type
+	typ    btf.Type
 }
 
 // paramFieldsFromNames initializes and returns a slice of field pointers based on the provided field names.
@@ -50,7 +67,7 @@ func paramFieldsFromNames(fields ...string) []*field {
 }
 
 // buildFieldsWithWrap builds the fields with the provided wrap.
-func buildFieldsWithWrap(spec btfSpec, wrap Wrap, fields []*field) error {
+func buildFieldsWithWrap(spec btfSpec, opts resolveOptions, wrap Wrap, fields []*field) error {
 
 	if len(fields) == 0 {
 		return ErrMissingFields
@@ -71,24 +88,13 @@ func buildFieldsWithWrap(spec btfSpec, wrap Wrap, fields []*field) error {
 	switch len(btfTypes) {
 	case 0:
 		return fmt.Errorf("getting func fieldsBuilder %s failed: %w", paramTypeToSearch.name, ErrFuncParamNotFound)
-	case 1:
-		btfTarget = btfTypes[0]
 	default:
-		// If we found multiple types, prioritize struct ones
-		for _, btfType := range btfTypes {
-			if btfTarget != nil {
-				break
-			}
-			switch t := btfType.(type) {
-			case *btf.Struct:
-				btfTarget = t
-			}
-		}
+		btfTarget = preferStructType(btfTypes)
+	}
 
-		// If no struct type found, use the first type in the list
-		if btfTarget == nil {
-			btfTarget = btfTypes[0]
-		}
+	if btfFunc, ok := btfTarget.(*btf.Func); ok {
+		// a function name stands for its prototype, thus wrapping it in a pointer results in a function pointer
+		btfTarget = btfFunc.Type
 	}
 
 	var fieldsToBuild []*field
@@ -106,6 +112,16 @@ func buildFieldsWithWrap(spec btfSpec, wrap Wrap, fields []*field) error {
 			Target: btfTarget,
 		}
 		baseBtfType = customPtr
+	case WrapUserPointer:
+		// Wrap the target type in a pointer that targets user-space memory
+		fieldsToBuild = fields[1:]
+		customPtr := &btf.Pointer{
+			Target: &btf.TypeTag{
+				Type:  btfTarget,
+				Value: userSpaceTypeTag,
+			},
+		}
+		baseBtfType = customPtr
 	case WrapStructPointer:
 		// Wrap the target type in an artificial struct pointer
 		// at offset 0
@@ -135,15 +151,32 @@ func buildFieldsWithWrap(spec btfSpec, wrap Wrap, fields []*field) error {
 	paramTypeToSearch.seen = true
 	paramTypeToSearch.includeInOffset = false
 	paramTypeToSearch.btfType = baseBtfType
+	paramTypeToSearch.valueBtfType = baseBtfType
+	paramTypeToSearch.bitfield = nil
+	paramTypeToSearch.userSpace = false
+	paramTypeToSearch.anonymousMembers = nil
+	paramTypeToSearch.memberName = ""
 
 	// Build the BTF representation of the fields recursively
-	if err = buildFieldsRecursive(spec, baseBtfType, 0, fieldsToBuild); err != nil {
+	if err = buildFieldsRecursive(spec, opts, baseBtfType, 0, false, fieldsToBuild); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// preferStructType returns the first struct of the given btf types, which are found by name, or the first
+// btf type if there is no struct.
+func preferStructType(btfTypes []btf.Type) btf.Type {
+	for _, btfType := range btfTypes {
+		if t, ok := btfType.(*btf.Struct); ok {
+			return t
+		}
+	}
+
+	return btfTypes[0]
+}
+
 func getArrayTypeSizeBytes(btfType btf.Type) uint32 {
 	switch t := btfType.(type) {
 	case *btf.Union:
@@ -160,18 +193,147 @@ func getArrayTypeSizeBytes(btfType btf.Type) uint32 {
 		return t.Size
 	case *btf.Pointer:
 		return 8
+	case *btf.Array:
+		return getArrayTypeSizeBytes(t.Type) * t.Nelems
 	case *btf.Typedef:
 		return getArrayTypeSizeBytes(t.Type)
 	case *btf.Const:
 		return getArrayTypeSizeBytes(t.Type)
+	case *btf.Volatile:
+		return getArrayTypeSizeBytes(t.Type)
+	case *btf.Restrict:
+		return getArrayTypeSizeBytes(t.Type)
+	case *btf.TypeTag:
+		return getArrayTypeSizeBytes(t.Type)
 	default:
 		return 0
 	}
 }
 
-// buildFieldsRecursive recursively builds fields based on the parent type and fields slice.
+// memberOffset returns the offset in bytes of the given member. If the member is a bitfield, the returned offset is
+// the one of the container that holds the bitfield and the respective bitfield layout is returned as well.
+func memberOffset(m btf.Member) (uint32, *bitfield, error) {
+	if m.BitfieldSize == 0 {
+		return m.Offset.Bytes(), nil, nil
+	}
+
+	return bitfieldFromMember(m)
+}
+
+// indexEnum returns the btf enum and the enum value name of the given array index field, which has the format of
+// "enum:<enum name>:<enum value name>". If the enum name is empty, e.g. "enum::PIDTYPE_PGID", the enum is searched
+// in the btf spec by the enum value name.
+func indexEnum(spec btfSpec, fieldName string) (*btf.Enum, string, error) {
+	enumTokens := strings.Split(fieldName, ":")
+	if len(enumTokens) != 3 || enumTokens[2] == "" {
+		return nil, "", fmt.Errorf("index from enum invalid format: %w", ErrArrayIndexInvalidField)
+	}
+
+	enumName := enumTokens[1]
+	enumValueName := enumTokens[2]
+
+	var btfEnum *btf.Enum
+	var err error
+	if enumName == "" {
+		btfEnum, err = spec.enumByValueName(enumValueName)
+	} else {
+		err = spec.TypeByName(enumName, &btfEnum)
+	}
+
+	if err != nil {
+		if errors.Is(err, btf.ErrNotFound) || err.Error() == "not found" {
+			return nil, "", fmt.Errorf("enum not found in spec: %w", ErrArrayIndexInvalidField)
+		}
+		return nil, "", err
+	}
+
+	return btfEnum, enumValueName, nil
+}
+
+// resolvedMember describes a struct or union member that a field name resolved to.
+type resolvedMember struct {
+	typ         btf.Type
+	offsetBytes uint32
+	bitfield    *bitfield
+	// holder is the anonymous struct or union that directly holds the member, if the member was found
+	// inside an anonymous member
+	holder btf.Type
+	// anonymousMembers are the anonymous members that were traversed to reach the member
+	anonymousMembers []anonymousMember
+}
+
+// compositeMembers returns the members of the given btf type if the latter is a struct or a union.
+func compositeMembers(btfType btf.Type) ([]btf.Member, bool) {
+	switch t := btfType.(type) {
+	case *btf.Struct:
+		return t.Members, true
+	case *btf.Union:
+		return t.Members, true
+	default:
+		return nil, false
+	}
+}
+
+// findMember searches the members of the given struct or union for the one with the given name. If there is no
+// such member and anonymousMembers is true, the anonymous struct and union members are searched recursively
+// and their offsets are accumulated to the offset of the found member. It returns nil if no member is found.
+func findMember(parent btf.Type, name string, anonymousMembers bool) (*resolvedMember, error) {
+	members, _ := compositeMembers(parent)
+	for _, m := range members {
+		if m.Name != name {
+			continue
+		}
+
+		offsetBytes, memberBitfield, err := memberOffset(m)
+		if err != nil {
+			return nil, err
+		}
+
+		return &resolvedMember{
+			typ:         m.Type,
+			offsetBytes: offsetBytes,
+			bitfield:    memberBitfield,
+		}, nil
+	}
+
+	if !anonymousMembers || name == "" {
+		return nil, nil
+	}
+
+	for _, m := range members {
+		if m.Name != "" {
+			continue
+		}
+
+		anonymousType := btf.UnderlyingType(m.Type)
+		if _, ok := compositeMembers(anonymousType); !ok {
+			continue
+		}
+
+		member, err := findMember(anonymousType, name, anonymousMembers)
+		if err != nil {
+			return nil, err
+		}
+
+		if member == nil {
+			continue
+		}
+
+		member.offsetBytes += m.Offset.Bytes()
+		if member.holder == nil {
+			member.holder = anonymousType
+		}
+		member.anonymousMembers = append([]anonymousMember{{parent: parent, typ: m.Type}}, member.anonymousMembers...)
+		return member, nil
+	}
+
+	return nil, nil
+}
+
+// buildFieldsRecursive recursively builds fields based on the parent type and fields slice. The userSpace argument
+// indicates that the parent resides in user-space memory, namely it was reached through a pointer tagged with __user.
 // It returns ErrFieldNotFound if any field is not found.
-func buildFieldsRecursive(spec btfSpec, parent btf.Type, parentOffsetBytes uint32, fields []*field) error {
+func buildFieldsRecursive(spec btfSpec, opts resolveOptions, parent btf.Type, parentOffsetBytes int64, userSpace bool, fields []*field) error {
 
 	// If there are no fields left, return nil.
 	if len(fields) == 0 {
@@ -182,45 +344,44 @@ func buildFieldsRecursive(spec btfSpec, parent btf.Type, parentOffsetBytes uint3
 
 	// Get the members based on the type of the parent.
 	var targetType btf.Type
-	var targetOffsetBytes uint32
+	var targetOffsetBytes int64
+	var targetBitfield *bitfield
+	var anonymousMembers []anonymousMember
+	var memberName string
 	switch t := parent.(type) {
-	case *btf.Struct:
-		for _, m := range t.Members {
-			if m.Name != fieldName {
-				continue
-			}
+	case *btf.Struct, *btf.Union:
+		if isContainerOfField(fieldName) {
+			return buildContainerOfField(spec, opts, parent, parentOffsetBytes, userSpace, fields)
+		}
 
-			targetType = m.Type
-			targetOffsetBytes = m.Offset.Bytes()
-			break
+		member, err := findMember(parent, fieldName, opts.anonymousMembers)
+		if err != nil {
+			return fmt.Errorf("getting field %s of type %s failed: %w", fieldName, parent.TypeName(), err)
 		}
-	case *btf.Union:
-		for _, m := range t.Members {
-			if m.Name != fieldName {
-				continue
+
+		if member == nil {
+			// the member may have been renamed, thus try its aliases
+			member, memberName, err = findMemberAlias(parent, fieldName, opts)
+			if err != nil {
+				return fmt.Errorf("getting field %s of type %s failed: %w", fieldName, parent.TypeName(), err)
 			}
+		}
 
-			targetType = m.Type
-			targetOffsetBytes = m.Offset.Bytes()
-			break
+		if member != nil {
+			targetType = member.typ
+			targetOffsetBytes = int64(member.offsetBytes)
+			targetBitfield = member.bitfield
+			anonymousMembers = member.anonymousMembers
+			if member.holder != nil {
+				parent = member.holder
+			}
 		}
 	case *btf.Array:
 		arrayIndex := uint64(0)
 		switch {
 		case strings.HasPrefix(fieldName, "enum:"):
-			enumTokens := strings.Split(fieldName, ":")
-			if len(enumTokens) != 3 {
-				return fmt.Errorf("index from enum invalid format: %w", ErrArrayIndexInvalidField)
-			}
-
-			enumName := enumTokens[1]
-			enumValueName := enumTokens[2]
-
-			var btfEnum *btf.Enum
-			if err := spec.TypeByName(enumName, &btfEnum); err != nil {
-				if errors.Is(err, btf.ErrNotFound) || err.Error() == "not found" {
-					return fmt.Errorf("enum not found in spec: %w", ErrArrayIndexInvalidField)
-				}
+			btfEnum, enumValueName, err := indexEnum(spec, fieldName)
+			if err != nil {
 				return err
 			}
 
@@ -257,14 +418,22 @@ func buildFieldsRecursive(spec btfSpec, parent btf.Type, parentOffsetBytes uint3
 		}
 
 		targetType = t.Type
-		targetOffsetBytes = getArrayTypeSizeBytes(targetType) * uint32(arrayIndex)
+		targetOffsetBytes = int64(getArrayTypeSizeBytes(targetType)) * int64(arrayIndex)
 
 	case *btf.Pointer:
 		// if the parent type is a ptr proceed by passing its target but make the offset 0
 		// since we are entering a new ptr
-		return buildFieldsRecursive(spec, t.Target, 0, fields)
+		return buildFieldsRecursive(spec, opts, t.Target, 0, isUserSpaceType(t.Target), fields)
 	case *btf.Const:
-		return buildFieldsRecursive(spec, t.Type, parentOffsetBytes, fields)
+		return buildFieldsRecursive(spec, opts, t.Type, parentOffsetBytes, userSpace, fields)
+	case *btf.Volatile:
+		return buildFieldsRecursive(spec, opts, t.Type, parentOffsetBytes, userSpace, fields)
+	case *btf.Restrict:
+		return buildFieldsRecursive(spec, opts, t.Type, parentOffsetBytes, userSpace, fields)
+	case *btf.Typedef:
+		return buildFieldsRecursive(spec, opts, t.Type, parentOffsetBytes, userSpace, fields)
+	case *btf.TypeTag:
+		return buildFieldsRecursive(spec, opts, t.Type, parentOffsetBytes, userSpace || t.Value == userSpaceTypeTag, fields)
 	}
 
 	// If the member type is nil, return an error.
@@ -272,40 +441,174 @@ func buildFieldsRecursive(spec btfSpec, parent btf.Type, parentOffsetBytes uint3
 		return fmt.Errorf("getting field %s of type %s failed: %w", fieldName, parent.TypeName(), ErrFieldNotFound)
 	}
 
-	// Handle different types of member types.
-	switch t := targetType.(type) {
+	// Handle different types of member types. Any qualifiers and typedefs wrapping the member type are skipped,
+	// so that e.g. a typedef of a struct is traversed as the struct itself.
+	switch t := btf.UnderlyingType(targetType).(type) {
 	case *btf.Pointer:
 		fields[0].offset = parentOffsetBytes + targetOffsetBytes
 		fields[0].seen = true
 		fields[0].includeInOffset = true
 		fields[0].btfType = t.Target
+		fields[0].valueBtfType = targetType
 		fields[0].parentBtfType = parent
+		fields[0].bitfield = nil
+		fields[0].userSpace = userSpace
+		fields[0].anonymousMembers = anonymousMembers
+		fields[0].memberName = memberName
 		// if the member type is a ptr proceed by passing its target but make the offset 0
 		// since we are entering a new ptr
-		return buildFieldsRecursive(spec, t.Target, 0, fields[1:])
-	case *btf.Array, *btf.Struct, *btf.Union, *btf.Const:
+		return buildFieldsRecursive(spec, opts, t.Target, 0, isUserSpaceType(t.Target), fields[1:])
+	case *btf.Array:
+		fields[0].offset = parentOffsetBytes + targetOffsetBytes
+		fields[0].seen = true
+		// an embedded array is fetched from its own address, thus when it is the last field its offset is included
+		fields[0].includeInOffset = len(fields) == 1
+		fields[0].btfType = targetType
+		fields[0].valueBtfType = targetType
+		fields[0].parentBtfType = parent
+		fields[0].bitfield = nil
+		fields[0].userSpace = userSpace
+		fields[0].anonymousMembers = anonymousMembers
+		fields[0].memberName = memberName
+		return buildFieldsRecursive(spec, opts, t, parentOffsetBytes+targetOffsetBytes, userSpace, fields[1:])
+	case *btf.Struct, *btf.Union:
+		fields[0].offset = parentOffsetBytes + targetOffsetBytes
 		fields[0].seen = true
 		fields[0].includeInOffset = false
-		fields[0].btfType = t
+		fields[0].btfType = targetType
+		fields[0].valueBtfType = targetType
 		fields[0].parentBtfType = parent
-		return buildFieldsRecursive(spec, targetType, parentOffsetBytes+targetOffsetBytes, fields[1:])
+		fields[0].bitfield = nil
+		fields[0].userSpace = userSpace
+		fields[0].anonymousMembers = anonymousMembers
+		fields[0].memberName = memberName
+		return buildFieldsRecursive(spec, opts, t, parentOffsetBytes+targetOffsetBytes, userSpace, fields[1:])
 	default:
 		fields[0].offset = parentOffsetBytes + targetOffsetBytes
 		fields[0].seen = true
 		fields[0].includeInOffset = true
-		fields[0].btfType = t
+		fields[0].btfType = targetType
+		fields[0].valueBtfType = targetType
 		fields[0].parentBtfType = parent
+		fields[0].bitfield = targetBitfield
+		fields[0].userSpace = userSpace
+		fields[0].anonymousMembers = anonymousMembers
+		fields[0].memberName = memberName
+
+		if len(fields) > 1 && isValueAlias(targetType, fields[1].name, opts) {
+			// the next field is an alias of the value itself, e.g. the val of a uid_t in kernels without kuid_t
+			*fields[1] = field{
+				name:         fields[1].name,
+				offset:       fields[0].offset,
+				seen:         true,
+				btfType:      targetType,
+				valueBtfType: targetType,
+				bitfield:     targetBitfield,
+				userSpace:    userSpace,
+			}
+		}
 		return nil
 	}
 }
 
+// isUserSpaceType returns true if the given btf type is tagged with __user, which means that it resides in
+// user-space memory. Any qualifiers or typedefs wrapping the type tag are skipped.
+func isUserSpaceType(btfType btf.Type) bool {
+	for {
+		switch t := btfType.(type) {
+		case *btf.TypeTag:
+			if t.Value == userSpaceTypeTag {
+				return true
+			}
+			btfType = t.Type
+		case *btf.Const:
+			btfType = t.Type
+		case *btf.Volatile:
+			btfType = t.Type
+		case *btf.Restrict:
+			btfType = t.Type
+		case *btf.Typedef:
+			btfType = t.Type
+		default:
+			return false
+		}
+	}
+}
+
+// fetchArgOffset returns the tracing fs representation of a dereference at the given offset, namely +N( or -N( for
+// negative offsets. If the dereference targets user-space memory, the offset is prefixed with u, e.g. +uN(.
+func fetchArgOffset(offset int64, userSpace bool) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+
+	if userSpace {
+		// the field resides in user-space memory
+		return fmt.Sprintf("%su%d(", sign, offset)
+	}
+
+	return fmt.Sprintf("%s%d(", sign, offset)
+}
+
+// leafBtfType returns the btf type of the value that the given fields resolve to. If there are no fields,
+// the value is the one of the root type, e.g. the function parameter or the function return.
+func leafBtfType(rootType btf.Type, fields []*field) btf.Type {
+	if len(fields) == 0 {
+		return rootType
+	}
+
+	return fields[len(fields)-1].valueBtfType
+}
+
+// leafFieldBitfield returns the bitfield layout of the last field, if the latter is a bitfield member.
+func leafFieldBitfield(fields []*field) *bitfield {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return fields[len(fields)-1].bitfield
+}
+
+// leafFieldIsArray returns true if the last field is an embedded array, which in contrast to a pointer
+// is fetched from its own address.
+func leafFieldIsArray(fields []*field) bool {
+	if len(fields) == 0 {
+		return false
+	}
+
+	leaf := fields[len(fields)-1]
+	if !leaf.includeInOffset {
+		return false
+	}
+
+	_, isArray := btf.UnderlyingType(leaf.valueBtfType).(*btf.Array)
+	return isArray
+}
+
+// leafInUserSpace returns true if the data that the fields resolve to reside in user-space memory. For an embedded
+// array this is the memory of the array itself, while for any other leaf this is the memory that its pointer
+// value points to.
+func leafInUserSpace(leafType btf.Type, fields []*field) bool {
+	if leafFieldIsArray(fields) {
+		return fields[len(fields)-1].userSpace
+	}
+
+	ptr, ok := btf.UnderlyingType(leafType).(*btf.Pointer)
+	if !ok {
+		return false
+	}
+
+	return isUserSpaceType(ptr.Target)
+}
+
 // buildTracingEventFromFields generates, based on the fields, the respective trace fs offsets alongside the
 // arch-specific register
 func buildTracingEventFromFields(probeType ProbeType, paramIndex int, fields []*field, regs registersResolver) (string, error) {
 	var (
 		registerStr string
 		err         error
-		eventParam  strings.Builder
 	)
 
 	switch probeType {
@@ -318,6 +621,13 @@ func buildTracingEventFromFields(probeType ProbeType, paramIndex int, fields []*
 		}
 	}
 
+	return buildDerefChain(registerStr, fields)
+}
+
+// buildDerefChain wraps the given root with the dereferences of the fields that are included in the offset.
+func buildDerefChain(root string, fields []*field) (string, error) {
+	var eventParam strings.Builder
+
 	// the first field is the last offset in the string representation, so we need to loop in reverse
 	offsetsCount := 0
 	for i := len(fields) - 1; i >= 0; i-- {
@@ -330,12 +640,12 @@ func buildTracingEventFromFields(probeType ProbeType, paramIndex int, fields []*
 			continue
 		}
 
-		eventParam.WriteString(fmt.Sprintf("+%d(", fld.offset))
+		eventParam.WriteString(fetchArgOffset(fld.offset, fld.userSpace))
 		offsetsCount++
 	}
 
-	// write the register
-	eventParam.WriteString(registerStr)
+	// write the root, e.g. the register
+	eventParam.WriteString(root)
 
 	// write all closing offset parentheses
 	for i := 0; i < offsetsCount; i++ {
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"github.com/cilium/ebpf/btf"
+)
+
+// fieldAliasKey returns the key of the field aliases of the given type name and field name.
+func fieldAliasKey(typeName string, fieldName string) string {
+	return typeName + "." + fieldName
+}
+
+// findMemberAlias searches the parent type for the first member that matches one of the aliases of the given field
+// name. It returns the resolved member alongside the name of the member, or nil if none of the aliases matches.
+func findMemberAlias(parent btf.Type, fieldName string, opts resolveOptions) (*resolvedMember, string, error) {
+	for _, alias := range opts.fieldAliases[fieldAliasKey(parent.TypeName(), fieldName)] {
+		if alias == "" {
+			continue
+		}
+
+		member, err := findMember(parent, alias, opts.anonymousMembers)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if member != nil {
+			return member, alias, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// isValueAlias returns true if the given field name is an alias of the value of the given type itself. Any typedef
+// that wraps the type is also considered, e.g. the field val of a uid_t.
+func isValueAlias(btfType btf.Type, fieldName string, opts resolveOptions) bool {
+	for btfType != nil {
+		for _, alias := range opts.fieldAliases[fieldAliasKey(btfType.TypeName(), fieldName)] {
+			if alias == "" {
+				return true
+			}
+		}
+
+		switch t := btfType.(type) {
+		case *btf.Typedef:
+			btfType = t.Type
+		case *btf.Const:
+			btfType = t.Type
+		case *btf.Volatile:
+			btfType = t.Type
+		case *btf.Restrict:
+			btfType = t.Type
+		case *btf.TypeTag:
+			btfType = t.Type
+		default:
+			return false
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// containerOfFieldPrefix is the prefix of a field that steps from an embedded member back to its container, with
+// the format of "container_of:<container type name>:<member name>".
+const containerOfFieldPrefix = "container_of:"
+
+// ContainerOf returns the field that steps from the current position of a field path, which must be the given member
+// of the given container type, back to the enclosing container, the same way as the container_of macro of the
+// kernel does, e.g. FuncParamWithName("node", ContainerOf("task_struct", "tasks"), "pid"). The member offset is
+// computed from the btf spec and subtracted from the current offset, thus the resulting tracing fs offsets may be
+// negative. A container_of field must be followed by at least one member of the container.
+func ContainerOf(containerType string, member string) string {
+	return containerOfFieldPrefix + containerType + ":" + member
+}
+
+// isContainerOfField returns true if the given field is a container_of field.
+func isContainerOfField(fieldName string) bool {
+	return strings.HasPrefix(fieldName, containerOfFieldPrefix)
+}
+
+// buildContainerOfField resolves the container_of field at the head of fields. The parent must be the type of the
+// container member, and parentOffsetBytes its offset. The remaining fields are resolved as members of the container.
+func buildContainerOfField(spec btfSpec, opts resolveOptions, parent btf.Type, parentOffsetBytes int64, userSpace bool, fields []*field) error {
+	fieldName := fields[0].name
+
+	tokens := strings.Split(fieldName, ":")
+	if len(tokens) != 3 || tokens[1] == "" || tokens[2] == "" {
+		return fmt.Errorf("container_of field %s invalid format: %w", fieldName, ErrInvalidContainerOf)
+	}
+
+	containerName := tokens[1]
+	memberName := tokens[2]
+
+	if len(fields) == 1 {
+		return fmt.Errorf("container_of field %s is not followed by a member: %w", fieldName, ErrInvalidContainerOf)
+	}
+
+	btfTypes, err := spec.AnyTypesByName(containerName)
+	if err != nil || len(btfTypes) == 0 {
+		return errors.Join(fmt.Errorf("getting container type %s failed: %w", containerName, ErrFieldNotFound), err)
+	}
+
+	container := btf.UnderlyingType(preferStructType(btfTypes))
+	if _, ok := compositeMembers(container); !ok {
+		return fmt.Errorf("container type %s is not a struct or union: %w", containerName, ErrInvalidContainerOf)
+	}
+
+	member, err := findMember(container, memberName, opts.anonymousMembers)
+	if err != nil {
+		return fmt.Errorf("getting field %s of type %s failed: %w", memberName, containerName, err)
+	}
+
+	if member == nil {
+		return fmt.Errorf("getting field %s of type %s failed: %w", memberName, containerName, ErrFieldNotFound)
+	}
+
+	if member.bitfield != nil {
+		return fmt.Errorf("member %s of type %s is a bitfield: %w", memberName, containerName, ErrInvalidContainerOf)
+	}
+
+	if !sameType(btf.UnderlyingType(member.typ), parent) {
+		return fmt.Errorf("member %s of type %s is of type %s instead of %s: %w", memberName, containerName,
+			member.typ.TypeName(), parent.TypeName(), ErrInvalidContainerOf)
+	}
+
+	holder := member.holder
+	if holder == nil {
+		holder = container
+	}
+
+	fields[0].offset = parentOffsetBytes - int64(member.offsetBytes)
+	fields[0].seen = true
+	fields[0].includeInOffset = false
+	fields[0].btfType = container
+	fields[0].valueBtfType = container
+	fields[0].parentBtfType = holder
+	fields[0].bitfield = nil
+	fields[0].userSpace = userSpace
+	fields[0].anonymousMembers = member.anonymousMembers
+	fields[0].memberName = memberName
+
+	return buildFieldsRecursive(spec, opts, container, parentOffsetBytes-int64(member.offsetBytes), userSpace, fields[1:])
+}
+
+// sameType returns true if the given btf types are the same type or, since they may originate from different
+// copies of the btf spec, of the same kind and name.
+func sameType(a btf.Type, b btf.Type) bool {
+	if a == b {
+		return true
+	}
+
+	return fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b) && a.TypeName() != "" && a.TypeName() == b.TypeName()
+}
@@ -103,7 +103,7 @@ func Test_buildFieldsWithWrap(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			spec := mockAnyTypesByNameOnAnything(c.anyTypesByName, c.err)
-			err := buildFieldsWithWrap(spec, c.wrap, c.fields)
+			err := buildFieldsWithWrap(spec, resolveOptions{}, c.wrap, c.fields)
 			require.ErrorIs(t, err, c.err)
 
 			if c.fields != nil {
@@ -257,6 +257,71 @@ func Test_getArrayTypeSizeBytes(t *testing.T) {
 				Nelems: 3,
 			},
 			expectedSizeBytes: 8,
+		}, {
+			name: "volatile_type",
+			btfArray: &btf.Array{
+				Type: &btf.Volatile{
+					Type: &btf.Int{
+						Size: 4,
+					},
+				},
+				Index: &btf.Int{
+					Size: 4,
+				},
+				Nelems: 3,
+			},
+			expectedSizeBytes: 4,
+		}, {
+			name: "restrict_type",
+			btfArray: &btf.Array{
+				Type: &btf.Restrict{
+					Type: &btf.Pointer{
+						Target: &btf.Void{},
+					},
+				},
+				Index: &btf.Int{
+					Size: 4,
+				},
+				Nelems: 3,
+			},
+			expectedSizeBytes: 8,
+		}, {
+			name: "type_tag_type",
+			btfArray: &btf.Array{
+				Type: &btf.TypeTag{
+					Type: &btf.Struct{
+						Size: 24,
+					},
+					Value: "user",
+				},
+				Index: &btf.Int{
+					Size: 4,
+				},
+				Nelems: 3,
+			},
+			expectedSizeBytes: 24,
+		}, {
+			name: "typedef_array_type",
+			btfArray: &btf.Array{
+				Type: &btf.Typedef{
+					Type: &btf.Array{
+						Type: &btf.Volatile{
+							Type: &btf.Int{
+								Size: 4,
+							},
+						},
+						Index: &btf.Int{
+							Size: 4,
+						},
+						Nelems: 2,
+					},
+				},
+				Index: &btf.Int{
+					Size: 4,
+				},
+				Nelems: 3,
+			},
+			expectedSizeBytes: 8,
 		},
 	}
 
@@ -31,7 +31,7 @@ type funcParamArbitrary struct {
 	funcParamAtIndex
 }
 
-func (p *funcParamArbitrary) build(spec btfSpec, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+func (p *funcParamArbitrary) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
 	var arg btf.FuncParam
 
 	// funcParamArbitrary is compatible only with ProbeTypeKProbe
@@ -62,7 +62,7 @@ func (p *funcParamArbitrary) build(spec btfSpec, probeType ProbeType, funcType *
 	}
 
 	// Build the fieldsBuilder at the given foundIndex.
-	return p.funcParamAtIndex.build(spec, probeType, funcType, regs)
+	return p.funcParamAtIndex.build(spec, opts, probeType, funcType, regs)
 }
 
 func (p *funcParamArbitrary) getFields() []*field {
@@ -72,3 +72,7 @@ func (p *funcParamArbitrary) getFields() []*field {
 func (p *funcParamArbitrary) getWrap() Wrap {
 	return p.wrap
 }
+
+func (p *funcParamArbitrary) getLeafType() btf.Type {
+	return leafBtfType(nil, p.fields)
+}
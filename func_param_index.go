@@ -29,13 +29,13 @@ type funcParamAtIndex struct {
 	wrap   Wrap
 }
 
-func (p *funcParamAtIndex) build(spec btfSpec, probeType ProbeType, _ *btf.Func, regs registersResolver) (string, error) {
+func (p *funcParamAtIndex) build(spec btfSpec, opts resolveOptions, probeType ProbeType, _ *btf.Func, regs registersResolver) (string, error) {
 	// funcParamAtIndex is compatible only with ProbeTypeKProbe
 	if probeType != ProbeTypeKProbe {
 		return "", ErrIncompatibleFetchArg
 	}
 
-	if err := buildFieldsWithWrap(spec, p.wrap, p.fields); err != nil {
+	if err := buildFieldsWithWrap(spec, opts, p.wrap, p.fields); err != nil {
 		return "", err
 	}
 
@@ -50,3 +50,7 @@ func (p *funcParamAtIndex) getFields() []*field {
 func (p *funcParamAtIndex) getWrap() Wrap {
 	return p.wrap
 }
+
+func (p *funcParamAtIndex) getLeafType() btf.Type {
+	return leafBtfType(nil, p.fields)
+}
@@ -29,9 +29,10 @@ type funcParamWithName struct {
 	foundIndex int
 	name       string
 	fields     []*field
+	btfType    btf.Type
 }
 
-func (p *funcParamWithName) build(spec btfSpec, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+func (p *funcParamWithName) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
 	var arg btf.FuncParam
 
 	// funcParamWithName is compatible only with ProbeTypeKProbe
@@ -60,8 +61,10 @@ func (p *funcParamWithName) build(spec btfSpec, probeType ProbeType, funcType *b
 		return "", fmt.Errorf("getting func fieldsBuilder failed: %w", ErrFuncParamNotFound)
 	}
 
+	p.btfType = arg.Type
+
 	// build fields recursively
-	if err := buildFieldsRecursive(spec, arg.Type, 0, p.fields); err != nil {
+	if err := buildFieldsRecursive(spec, opts, arg.Type, 0, false, p.fields); err != nil {
 		return "", err
 	}
 
@@ -76,3 +79,7 @@ func (p *funcParamWithName) getFields() []*field {
 func (p *funcParamWithName) getWrap() Wrap {
 	return WrapNone
 }
+
+func (p *funcParamWithName) getLeafType() btf.Type {
+	return leafBtfType(p.btfType, p.fields)
+}
@@ -0,0 +1,190 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// funcParamWithType is the implementation of the fieldsBuilder interface for constructing function parameter
+// that matches the given type name and occurrence among the parameters of the same type inside the func prototype
+// of the btf spec.
+type funcParamWithType struct {
+	foundIndex int
+	typeName   string
+	occurrence int
+	fields     []*field
+	btfType    btf.Type
+}
+
+func (p *funcParamWithType) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+	// funcParamWithType is compatible only with ProbeTypeKProbe
+	if probeType != ProbeTypeKProbe {
+		return "", ErrIncompatibleFetchArg
+	}
+
+	funcProtoType, ok := funcType.Type.(*btf.FuncProto)
+	if !ok {
+		return "", fmt.Errorf("btf func type is not a func proto %w", ErrFuncParamNotFound)
+	}
+
+	// Iterate through the function parameters to find the occurrence of the parameters with the specified type
+	p.foundIndex = -1
+	p.btfType = nil
+	occurrence := 0
+	for i, funcParam := range funcProtoType.Params {
+		if !typeNameMatches(funcParam.Type, p.typeName) {
+			continue
+		}
+
+		if occurrence == p.occurrence {
+			p.foundIndex = i
+			p.btfType = funcParam.Type
+			break
+		}
+		occurrence++
+	}
+
+	if p.btfType == nil {
+		return "", fmt.Errorf("getting func parameter of type %s at occurrence %d failed: %w", p.typeName, p.occurrence, ErrFuncParamNotFound)
+	}
+
+	// build fields recursively
+	if err := buildFieldsRecursive(spec, opts, p.btfType, 0, false, p.fields); err != nil {
+		return "", err
+	}
+
+	// Build the tracing string for the fieldsBuilder
+	return buildTracingEventFromFields(probeType, p.foundIndex, p.fields, regs)
+}
+
+func (p *funcParamWithType) getFields() []*field {
+	return p.fields
+}
+
+func (p *funcParamWithType) getWrap() Wrap {
+	return WrapNone
+}
+
+func (p *funcParamWithType) getLeafType() btf.Type {
+	return leafBtfType(p.btfType, p.fields)
+}
+
+// funcReturnWithType is the implementation of the fieldsBuilder interface for constructing function return relying
+// on the function prototype inside the btf spec, as long as the return type matches the given type name.
+type funcReturnWithType struct {
+	typeName string
+	funcReturn
+}
+
+func (p *funcReturnWithType) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+	// funcReturnWithType is compatible only with ProbeTypeKRetProbe
+	if probeType != ProbeTypeKRetProbe {
+		return "", ErrIncompatibleFetchArg
+	}
+
+	funcProtoType, ok := funcType.Type.(*btf.FuncProto)
+	if !ok {
+		return "", fmt.Errorf("btf func type is not a func proto %w", ErrFuncParamNotFound)
+	}
+
+	if !typeNameMatches(funcProtoType.Return, p.typeName) {
+		return "", fmt.Errorf("func return type %s differs from %s: %w", cTypeName(funcProtoType.Return, false), p.typeName, ErrFuncParamNotFound)
+	}
+
+	return p.funcReturn.build(spec, opts, probeType, funcType, regs)
+}
+
+// typeNameMatches returns true if the C declaration of the given btf type matches the given type name, e.g.
+// "struct inode *". Qualifiers and type tags are ignored, and the type name can refer to either the typedef or the
+// type it resolves to, e.g. both "u32" and "unsigned int" match a u32 btf type. Base types are named as in the btf
+// spec, e.g. "long unsigned int".
+func typeNameMatches(btfType btf.Type, typeName string) bool {
+	name := normalizeTypeName(typeName)
+	return name == cTypeName(btfType, false) || name == cTypeName(btfType, true)
+}
+
+// normalizeTypeName returns the given C type name without qualifiers and with single spaces between its tokens,
+// e.g. "const struct qstr*" becomes "struct qstr *".
+func normalizeTypeName(typeName string) string {
+	var tokens []string
+	for _, token := range strings.Fields(strings.ReplaceAll(typeName, "*", " * ")) {
+		if isTypeQualifier(token) {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	return strings.Join(tokens, " ")
+}
+
+// isTypeQualifier returns true if the given token of a C type name is a qualifier or a type tag.
+func isTypeQualifier(token string) bool {
+	switch token {
+	case "const", "volatile", "restrict", "__user":
+		return true
+	default:
+		return false
+	}
+}
+
+// cTypeName returns the normalized C declaration of the given btf type, e.g. "struct inode *". When resolveTypedefs
+// is true, typedefs are replaced by the type they resolve to.
+func cTypeName(btfType btf.Type, resolveTypedefs bool) string {
+	return strings.Join(cTypeTokens(btfType, resolveTypedefs), " ")
+}
+
+// cTypeTokens returns the tokens of the normalized C declaration of the given btf type.
+func cTypeTokens(btfType btf.Type, resolveTypedefs bool) []string {
+	switch t := btfType.(type) {
+	case nil:
+		return nil
+	case *btf.Pointer:
+		return append(cTypeTokens(t.Target, resolveTypedefs), "*")
+	case *btf.Const:
+		return cTypeTokens(t.Type, resolveTypedefs)
+	case *btf.Volatile:
+		return cTypeTokens(t.Type, resolveTypedefs)
+	case *btf.Restrict:
+		return cTypeTokens(t.Type, resolveTypedefs)
+	case *btf.TypeTag:
+		return cTypeTokens(t.Type, resolveTypedefs)
+	case *btf.Typedef:
+		if resolveTypedefs {
+			return cTypeTokens(t.Type, resolveTypedefs)
+		}
+		return []string{t.Name}
+	case *btf.Array:
+		return append(cTypeTokens(t.Type, resolveTypedefs), "["+strconv.FormatUint(uint64(t.Nelems), 10)+"]")
+	case *btf.Struct:
+		return []string{"struct", t.Name}
+	case *btf.Union:
+		return []string{"union", t.Name}
+	case *btf.Enum:
+		return []string{"enum", t.Name}
+	case *btf.Fwd:
+		return []string{t.Kind.String(), t.Name}
+	case *btf.Void:
+		return []string{"void"}
+	default:
+		return strings.Fields(t.TypeName())
+	}
+}
@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_typeNameMatches(t *testing.T) {
+	u32Type := &btf.Typedef{
+		Name: "u32",
+		Type: &btf.Int{
+			Name: "unsigned int",
+			Size: 4,
+		},
+	}
+	inodeType := &btf.Struct{
+		Name: "inode",
+	}
+
+	cases := []struct {
+		name     string
+		btfType  btf.Type
+		typeName string
+		matches  bool
+	}{
+		{
+			name: "struct_pointer",
+			btfType: &btf.Pointer{
+				Target: inodeType,
+			},
+			typeName: "struct inode *",
+			matches:  true,
+		},
+		{
+			name: "struct_pointer_no_space",
+			btfType: &btf.Pointer{
+				Target: inodeType,
+			},
+			typeName: "struct  inode*",
+			matches:  true,
+		},
+		{
+			name: "const_struct_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Const{
+					Type: inodeType,
+				},
+			},
+			typeName: "struct inode *",
+			matches:  true,
+		},
+		{
+			name: "struct_pointer_qualified_name",
+			btfType: &btf.Pointer{
+				Target: inodeType,
+			},
+			typeName: "const struct inode *",
+			matches:  true,
+		},
+		{
+			name: "user_char_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.TypeTag{
+					Type:  &btf.Int{Name: "char", Size: 1},
+					Value: userSpaceTypeTag,
+				},
+			},
+			typeName: "const char __user *",
+			matches:  true,
+		},
+		{
+			name:     "typedef",
+			btfType:  u32Type,
+			typeName: "u32",
+			matches:  true,
+		},
+		{
+			name:     "typedef_resolved",
+			btfType:  u32Type,
+			typeName: "unsigned int",
+			matches:  true,
+		},
+		{
+			name: "pointer_to_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Pointer{
+					Target: inodeType,
+				},
+			},
+			typeName: "struct inode *",
+			matches:  false,
+		},
+		{
+			name:     "struct_not_pointer",
+			btfType:  inodeType,
+			typeName: "struct inode *",
+			matches:  false,
+		},
+		{
+			name: "union_instead_of_struct",
+			btfType: &btf.Pointer{
+				Target: &btf.Union{Name: "inode"},
+			},
+			typeName: "struct inode *",
+			matches:  false,
+		},
+		{
+			name: "enum",
+			btfType: &btf.Enum{
+				Name: "fsnotify_data_type",
+				Size: 4,
+			},
+			typeName: "enum fsnotify_data_type",
+			matches:  true,
+		},
+		{
+			name: "void_pointer",
+			btfType: &btf.Pointer{
+				Target: &btf.Void{},
+			},
+			typeName: "const void *",
+			matches:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.matches, typeNameMatches(c.btfType, c.typeName))
+		})
+	}
+}
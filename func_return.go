@@ -26,11 +26,12 @@ import (
 // funcReturn is the implementation of the fieldsBuilder interface for constructing function return relying
 // on the function prototype inside the btf spec.
 type funcReturn struct {
-	fields []*field
+	fields  []*field
+	btfType btf.Type
 }
 
 // build
-func (p *funcReturn) build(spec btfSpec, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
+func (p *funcReturn) build(spec btfSpec, opts resolveOptions, probeType ProbeType, funcType *btf.Func, regs registersResolver) (string, error) {
 
 	// funcReturn is compatible only with ProbeTypeKRetProbe
 	if probeType != ProbeTypeKRetProbe {
@@ -43,8 +44,10 @@ func (p *funcReturn) build(spec btfSpec, probeType ProbeType, funcType *btf.Func
 		return "", fmt.Errorf("btf func type is not a func proto %w", ErrFuncParamNotFound)
 	}
 
+	p.btfType = funcProtoType.Return
+
 	// If there are fields defined for the fieldsBuilder, build them recursively
-	if err := buildFieldsRecursive(spec, funcProtoType.Return, 0, p.fields); err != nil {
+	if err := buildFieldsRecursive(spec, opts, funcProtoType.Return, 0, false, p.fields); err != nil {
 		return "", err
 	}
 
@@ -59,3 +62,7 @@ func (p *funcReturn) getFields() []*field {
 func (p *funcReturn) getWrap() Wrap {
 	return WrapNone
 }
+
+func (p *funcReturn) getLeafType() btf.Type {
+	return leafBtfType(p.btfType, p.fields)
+}
@@ -28,7 +28,7 @@ type funcReturnArbitrary struct {
 	fields []*field
 }
 
-func (p *funcReturnArbitrary) build(spec btfSpec, probeType ProbeType, _ *btf.Func, regs registersResolver) (string, error) {
+func (p *funcReturnArbitrary) build(spec btfSpec, opts resolveOptions, probeType ProbeType, _ *btf.Func, regs registersResolver) (string, error) {
 
 	// funcReturn is compatible only with ProbeTypeKRetProbe
 	if probeType != ProbeTypeKRetProbe {
@@ -36,7 +36,7 @@ func (p *funcReturnArbitrary) build(spec btfSpec, probeType ProbeType, _ *btf.Fu
 	}
 
 	// If there are fields defined for the fieldsBuilder, build them recursively
-	if err := buildFieldsWithWrap(spec, p.wrap, p.fields); err != nil {
+	if err := buildFieldsWithWrap(spec, opts, p.wrap, p.fields); err != nil {
 		return "", err
 	}
 
@@ -51,3 +51,7 @@ func (p *funcReturnArbitrary) getFields() []*field {
 func (p *funcReturnArbitrary) getWrap() Wrap {
 	return p.wrap
 }
+
+func (p *funcReturnArbitrary) getLeafType() btf.Type {
+	return leafBtfType(nil, p.fields)
+}
@@ -0,0 +1,117 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// perCPUDatasec is the name of the btf Datasec that holds the per-cpu kernel variables. The symbol address of
+// a per-cpu variable is an offset into the per-cpu area, thus it can't be fetched with @symbol.
+const perCPUDatasec = ".data..percpu"
+
+// globalVariable is the implementation of the fieldsBuilder interface for constructing fields of a kernel global
+// variable, which is resolved through the btf Var and Datasec entries. The first field is always the variable itself.
+type globalVariable struct {
+	name    string
+	fields  []*field
+	btfVar  *btf.Var
+	datasec *btf.Datasec
+}
+
+// build resolves the btf Var of the global variable and builds the fields as members of its type.
+func (p *globalVariable) build(spec btfSpec, opts resolveOptions, _ ProbeType, _ *btf.Func, _ registersResolver) (string, error) {
+	p.btfVar = nil
+	p.datasec = nil
+
+	var btfVar *btf.Var
+	if err := spec.TypeByName(p.name, &btfVar); err != nil {
+		return "", errors.Join(fmt.Errorf("getting global variable %s failed: %w", p.name, ErrGlobalVariableNotFound), err)
+	}
+
+	datasec, err := spec.datasecOfVar(btfVar)
+	if err != nil {
+		return "", err
+	}
+
+	if datasec != nil && datasec.Name == perCPUDatasec {
+		return "", fmt.Errorf("global variable %s is per-cpu: %w", p.name, ErrUnsupportedGlobalVariable)
+	}
+
+	p.btfVar = btfVar
+	p.datasec = datasec
+
+	// the variable is modelled as the member at offset zero of an artificial struct which is reached through
+	// the symbol address, so that the fields of the variable are resolved as for any other member
+	varStruct := &btf.Struct{
+		Name: "__global_variable",
+		Size: getArrayTypeSizeBytes(btfVar.Type),
+		Members: []btf.Member{
+			{
+				Name:         p.name,
+				Type:         btfVar.Type,
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+		},
+	}
+
+	if err := buildFieldsRecursive(spec, opts, &btf.Pointer{Target: varStruct}, 0, false, p.fields); err != nil {
+		return "", err
+	}
+
+	// the innermost dereference reads from the symbol address, thus it is expressed as @symbol+offset
+	for i, fld := range p.fields {
+		if !fld.includeInOffset {
+			continue
+		}
+
+		return buildDerefChain(symbolOffset(p.name, fld.offset), p.fields[i+1:])
+	}
+
+	return "", fmt.Errorf("global variable %s fields resolve to an address instead of a value: %w", p.name, ErrUnsupportedGlobalVariable)
+}
+
+func (p *globalVariable) getFields() []*field {
+	return p.fields
+}
+
+// getWrap returns WrapStructPointer since the variable is the member of an artificial struct.
+func (p *globalVariable) getWrap() Wrap {
+	return WrapStructPointer
+}
+
+func (p *globalVariable) getLeafType() btf.Type {
+	return leafBtfType(nil, p.fields)
+}
+
+// symbolOffset returns the tracing fs representation of the memory at the given offset from the symbol address,
+// namely @symbol, @symbol+N or @symbol-N.
+func symbolOffset(symbol string, offset int64) string {
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("@%s+%d", symbol, offset)
+	case offset < 0:
+		return fmt.Sprintf("@%s%d", symbol, offset)
+	default:
+		return "@" + symbol
+	}
+}
@@ -0,0 +1,322 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// KprobeEvent is the parsed representation of a kprobe event definition as found in the kprobe_events and
+// dynamic_events files of the tracing fs (https://docs.kernel.org/trace/kprobetrace.html#synopsis-of-kprobe-events),
+// e.g. p:kprobes/myprobe do_sys_open+16 dfd=%di:s32.
+type KprobeEvent struct {
+	// Type is ProbeTypeKProbe for p and ProbeTypeKRetProbe for r events.
+	Type ProbeType
+	// MaxActive is the maximum number of instances of a kretprobe that can run concurrently, e.g. 10 for r10,
+	// zero when not specified.
+	MaxActive int
+	// Group is the group of the event, empty if not specified.
+	Group string
+	// Event is the name of the event, empty if not specified.
+	Event string
+	// Symbol is the probed symbol, optionally prefixed with its module, e.g. ext4:ext4_file_open, or the probed
+	// address, e.g. 0xffffffff81000000.
+	Symbol string
+	// Offset is the offset from the symbol that is probed.
+	Offset int64
+	// FetchArgs holds the fetch args of the event in the order they are defined.
+	FetchArgs []*KprobeFetchArg
+}
+
+// ParseKprobeEvent parses the given kprobe event definition. When the definition is malformed or it is not a kprobe
+// or kretprobe event, e.g. a uprobe, an ErrInvalidKprobeEvent error is returned.
+func ParseKprobeEvent(line string) (*KprobeEvent, error) {
+	tokens := strings.Fields(line)
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("kprobe event %q is missing the symbol: %w", line, ErrInvalidKprobeEvent)
+	}
+
+	event := &KprobeEvent{}
+
+	prefix, name, hasName := strings.Cut(tokens[0], ":")
+	switch {
+	case prefix == "p":
+		event.Type = ProbeTypeKProbe
+	case strings.HasPrefix(prefix, "r"):
+		event.Type = ProbeTypeKRetProbe
+		if maxActive := prefix[1:]; maxActive != "" {
+			var err error
+			event.MaxActive, err = strconv.Atoi(maxActive)
+			if err != nil || event.MaxActive < 0 {
+				return nil, fmt.Errorf("kprobe event %q has invalid maxactive %s: %w", line, maxActive, ErrInvalidKprobeEvent)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("kprobe event %q has unsupported prefix %s: %w", line, prefix, ErrInvalidKprobeEvent)
+	}
+
+	if hasName {
+		group, eventName, hasGroup := strings.Cut(name, "/")
+		if hasGroup {
+			event.Group = group
+			event.Event = eventName
+		} else {
+			event.Event = name
+		}
+
+		if event.Event == "" || (hasGroup && event.Group == "") {
+			return nil, fmt.Errorf("kprobe event %q has invalid name %s: %w", line, name, ErrInvalidKprobeEvent)
+		}
+	}
+
+	event.Symbol = tokens[1]
+	if symbol, offset, hasOffset := cutSymbolOffset(tokens[1]); hasOffset {
+		parsedOffset, err := strconv.ParseInt(offset, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("kprobe event %q has invalid offset %s: %w", line, offset, ErrInvalidKprobeEvent)
+		}
+
+		event.Symbol = symbol
+		event.Offset = parsedOffset
+	}
+
+	for _, token := range tokens[2:] {
+		fetchArg, err := ParseKprobeFetchArg(token)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("kprobe event %q: %w", line, ErrInvalidKprobeEvent), err)
+		}
+
+		event.FetchArgs = append(event.FetchArgs, fetchArg)
+	}
+
+	return event, nil
+}
+
+// ParseKprobeEvents parses the kprobe event definitions of the given reader, one per line, e.g. the contents of
+// the kprobe_events file. Empty lines and comments are skipped. When a definition is malformed, parsing stops
+// and an ErrInvalidKprobeEvent error that points to the line number is returned.
+func ParseKprobeEvents(rd io.Reader) ([]*KprobeEvent, error) {
+	var events []*KprobeEvent
+
+	scanner := bufio.NewScanner(rd)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		event, err := ParseKprobeEvent(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// NewKprobeEvent returns the KprobeEvent of the given Probe, after the latter is built, with the given group and
+// the ID of the Probe as the event name. This is useful to diff the probes that tk-btf builds against the ones
+// already defined on a host.
+func NewKprobeEvent(group string, p *Probe) (*KprobeEvent, error) {
+	event := &KprobeEvent{
+		Type:   p.GetType(),
+		Group:  group,
+		Event:  p.GetID(),
+		Symbol: p.GetSymbolName(),
+	}
+
+	for _, token := range strings.Fields(p.GetTracingEventProbe()) {
+		fetchArg, err := ParseKprobeFetchArg(token)
+		if err != nil {
+			return nil, err
+		}
+
+		event.FetchArgs = append(event.FetchArgs, fetchArg)
+	}
+
+	return event, nil
+}
+
+// String returns the kprobe event definition, as accepted by the kprobe_events file of the tracing fs.
+func (e *KprobeEvent) String() string {
+	var line strings.Builder
+
+	switch e.Type {
+	case ProbeTypeKProbe:
+		line.WriteString("p")
+	case ProbeTypeKRetProbe:
+		line.WriteString("r")
+		if e.MaxActive > 0 {
+			line.WriteString(strconv.Itoa(e.MaxActive))
+		}
+	}
+
+	if e.Event != "" {
+		line.WriteString(":")
+		if e.Group != "" {
+			line.WriteString(e.Group)
+			line.WriteString("/")
+		}
+		line.WriteString(e.Event)
+	}
+
+	line.WriteString(" ")
+	line.WriteString(e.Symbol)
+	if e.Offset != 0 {
+		line.WriteString(fmt.Sprintf("+%d", e.Offset))
+	}
+
+	for _, fetchArg := range e.FetchArgs {
+		line.WriteString(" ")
+		line.WriteString(fetchArg.String())
+	}
+
+	return line.String()
+}
+
+// SymbolFromKprobeEvent migrates the given kprobe event into a Symbol with a single Probe, whose Ref is the event
+// name without any kprobe_ or kretprobe_ prefix, so that the events of NewKprobeEvent keep their name. Every fetch
+// arg is symbolized, see SymbolizeFetchArg, and the resulting field path is attached with the respective
+// fieldsBuilder, e.g. FuncParamWithName, FuncReturn or GlobalVariable, so that the Probe resolves against other
+// kernels. Fetch args without a name are named after their position, as the tracing fs does, e.g. arg1, and fetch
+// args without a type get the x64 type. The module prefix of the symbol, e.g. ext4:, is dropped, since the btf
+// spec names the function without it. A user-space dereference, e.g. +u8(...), is only migrated when the btf spec
+// tags the dereferenced pointer with __user, otherwise an ErrUntaggedUserSpace error is returned. A Probe always
+// attaches at the entry of the symbol and doesn't set the maxactive of a kretprobe, thus an event with an Offset or
+// a MaxActive returns an ErrInvalidKprobeEvent error instead of silently changing what is probed.
+func (s *Spec) SymbolFromKprobeEvent(event *KprobeEvent) (*Symbol, error) {
+	if event.Offset != 0 {
+		return nil, fmt.Errorf("kprobe event of %s+%d can't be migrated with an offset: %w", event.Symbol, event.Offset, ErrInvalidKprobeEvent)
+	}
+
+	if event.MaxActive != 0 {
+		return nil, fmt.Errorf("kprobe event of %s can't be migrated with maxactive %d: %w", event.Symbol, event.MaxActive, ErrInvalidKprobeEvent)
+	}
+
+	var probe *Probe
+	var idPrefix string
+	switch event.Type {
+	case ProbeTypeKRetProbe:
+		probe = NewKRetProbe()
+		idPrefix = "kretprobe_"
+	default:
+		probe = NewKProbe()
+		idPrefix = "kprobe_"
+	}
+
+	if event.Event != "" {
+		// the ID of the Probe prefixes the Ref with the probe type, thus strip it to get back the same event name
+		probe.SetRef(strings.TrimPrefix(event.Event, idPrefix))
+	}
+
+	symbolName := event.Symbol
+	if _, name, hasModule := strings.Cut(symbolName, ":"); hasModule {
+		symbolName = name
+	}
+
+	for i, kprobeFetchArg := range event.FetchArgs {
+		symbolized, err := s.SymbolizeFetchArg(symbolName, event.Type, kprobeFetchArg)
+		if err != nil {
+			return nil, err
+		}
+
+		if symbolized.UntaggedUserSpace {
+			return nil, fmt.Errorf("fetch arg %s of %s can't be migrated: %w", kprobeFetchArg.Name, symbolName, ErrUntaggedUserSpace)
+		}
+
+		argName := kprobeFetchArg.Name
+		if argName == "" {
+			argName = fmt.Sprintf("arg%d", i+1)
+		}
+
+		argType := kprobeFetchArg.Type
+		if argType == "" {
+			argType = "x64"
+		}
+
+		fetchArg, err := fetchArgFromSymbolized(NewFetchArg(argName, argType), kprobeFetchArg, symbolized)
+		if err != nil {
+			return nil, err
+		}
+
+		probe.AddFetchArgs(fetchArg)
+	}
+
+	return NewSymbol(symbolName).AddProbes(probe), nil
+}
+
+// fetchArgFromSymbolized attaches to the given fetchArg the fieldsBuilder that resolves the given symbolized kprobe
+// fetch arg.
+func fetchArgFromSymbolized(f *fetchArg, kprobeFetchArg *KprobeFetchArg, symbolized *SymbolizedFetchArg) (*fetchArg, error) {
+	root := kprobeFetchArg.Root
+	noDerefs := len(kprobeFetchArg.Derefs) == 0
+
+	switch {
+	case strings.HasPrefix(root, "@"):
+		return f.GlobalVariable(root[1:], symbolized.Fields...), nil
+	case symbolized.Param == "$retval" && root == "$retval" && noDerefs:
+		return f.FuncReturnValue(), nil
+	case symbolized.Param == "$retval":
+		return f.FuncReturn(symbolized.Fields...), nil
+	case symbolized.ParamIndex >= 0 && strings.HasPrefix(root, "$arg") && noDerefs:
+		return f.FuncParamValue(symbolized.ParamIndex), nil
+	case symbolized.ParamIndex >= 0:
+		return f.FuncParamWithName(symbolized.Param, symbolized.Fields...), nil
+	case root == "$comm" || root == "$COMM":
+		return f.Comm(), nil
+	case root == "$stack":
+		return f.Stack(), nil
+	case strings.HasPrefix(root, "$stack"):
+		index, err := strconv.Atoi(strings.TrimPrefix(root, "$stack"))
+		if err != nil {
+			return nil, fmt.Errorf("fetch arg %s has invalid stack entry %s: %w", kprobeFetchArg.Name, root, ErrInvalidKprobeFetchArg)
+		}
+		return f.StackEntry(index), nil
+	case strings.HasPrefix(root, `\"`):
+		return f.ImmediateString(strings.TrimSuffix(strings.TrimPrefix(root, `\"`), `"`)), nil
+	case strings.HasPrefix(root, `\`):
+		value, err := strconv.ParseInt(strings.TrimPrefix(root, `\`), 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("fetch arg %s has invalid immediate value %s: %w", kprobeFetchArg.Name, root, ErrInvalidKprobeFetchArg)
+		}
+		return f.Immediate(value), nil
+	default:
+		return nil, fmt.Errorf("fetch arg %s has unsupported root %s: %w", kprobeFetchArg.Name, root, ErrInvalidKprobeFetchArg)
+	}
+}
+
+// cutSymbolOffset splits the given probe point, e.g. do_sys_open+0x10, into the symbol and the offset.
+func cutSymbolOffset(probePoint string) (string, string, bool) {
+	plusIndex := strings.LastIndexByte(probePoint, '+')
+	if plusIndex <= 0 {
+		return probePoint, "", false
+	}
+
+	return probePoint[:plusIndex], probePoint[plusIndex+1:], true
+}
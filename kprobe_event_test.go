@@ -0,0 +1,239 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKprobeEvent(t *testing.T) {
+	tcs := []struct {
+		name     string
+		line     string
+		expected *KprobeEvent
+		err      error
+	}{
+		{
+			name: "kprobe",
+			line: "p:kprobes/myprobe do_sys_open dfd=%di:s32 fn=+0(%si):string",
+			expected: &KprobeEvent{
+				Type:   ProbeTypeKProbe,
+				Group:  "kprobes",
+				Event:  "myprobe",
+				Symbol: "do_sys_open",
+				FetchArgs: []*KprobeFetchArg{
+					{Name: "dfd", Root: "%di", Type: "s32"},
+					{Name: "fn", Root: "%si", Derefs: []KprobeDeref{{Offset: 0}}, Type: "string"},
+				},
+			},
+		},
+		{
+			name: "kretprobe_with_maxactive",
+			line: "r10:myretprobe do_sys_open $retval:s64",
+			expected: &KprobeEvent{
+				Type:      ProbeTypeKRetProbe,
+				MaxActive: 10,
+				Event:     "myretprobe",
+				Symbol:    "do_sys_open",
+				FetchArgs: []*KprobeFetchArg{
+					{Name: "", Root: "$retval", Type: "s64"},
+				},
+			},
+		},
+		{
+			name: "module_symbol_with_offset",
+			line: "p ext4:ext4_file_open+16",
+			expected: &KprobeEvent{
+				Type:   ProbeTypeKProbe,
+				Symbol: "ext4:ext4_file_open",
+				Offset: 16,
+			},
+		},
+		{
+			name: "unsupported_prefix",
+			line: "u:uprobes/myprobe /bin/bash:0x4245c0",
+			err:  ErrInvalidKprobeEvent,
+		},
+		{
+			name: "missing_symbol",
+			line: "p:kprobes/myprobe",
+			err:  ErrInvalidKprobeEvent,
+		},
+		{
+			name: "invalid_fetch_arg",
+			line: "p:kprobes/myprobe do_sys_open dfd=+8(%di:s32",
+			err:  ErrInvalidKprobeFetchArg,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := ParseKprobeEvent(tc.line)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, event)
+			require.Equal(t, tc.line, event.String())
+		})
+	}
+}
+
+func TestParseKprobeEvents(t *testing.T) {
+	events, err := ParseKprobeEvents(strings.NewReader(`# existing probes
+p:kprobes/myprobe do_sys_open dfd=%di:s32
+
+r:kprobes/myretprobe do_sys_open $retval:s64
+`))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "myprobe", events[0].Event)
+	require.Equal(t, "myretprobe", events[1].Event)
+
+	_, err = ParseKprobeEvents(strings.NewReader("p:kprobes/myprobe do_sys_open\nx:kprobes/invalid do_sys_open\n"))
+	require.ErrorIs(t, err, ErrInvalidKprobeEvent)
+	require.ErrorContains(t, err, "line 2")
+}
+
+func TestSpec_SymbolFromKprobeEvent(t *testing.T) {
+	spec := generateBTFSpec()
+
+	probe := NewKProbe().AddFetchArgs(
+		NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+		NewFetchArg("fn", "string").FuncParamWithName("dentry_param", "d_name", "name"),
+		NewFetchArg("lvl", "u32").GlobalVariable("init_pid_ns", "level"),
+		NewFetchArg("comm", "string").Comm(),
+		NewFetchArg("a2", "x64").FuncParamValue(1),
+	)
+	require.NoError(t, spec.BuildSymbol(NewSymbol("test_function").AddProbes(probe)))
+
+	event, err := NewKprobeEvent("tkbtf", probe)
+	require.NoError(t, err)
+
+	parsedEvent, err := ParseKprobeEvent(event.String())
+	require.NoError(t, err)
+	require.Equal(t, event, parsedEvent)
+
+	symbol, err := spec.SymbolFromKprobeEvent(parsedEvent)
+	require.NoError(t, err)
+	require.NoError(t, spec.BuildSymbol(symbol))
+
+	migratedEvent, err := NewKprobeEvent("tkbtf", symbol.GetProbes()[0])
+	require.NoError(t, err)
+	require.Equal(t, event.String(), migratedEvent.String())
+
+	// the module prefix of the symbol is not part of the btf func name
+	moduleEvent := *parsedEvent
+	moduleEvent.Symbol = "testmod:" + parsedEvent.Symbol
+
+	symbol, err = spec.SymbolFromKprobeEvent(&moduleEvent)
+	require.NoError(t, err)
+	require.NoError(t, spec.BuildSymbol(symbol))
+	require.Equal(t, "test_function", symbol.GetProbes()[0].GetSymbolName())
+
+	migratedEvent, err = NewKprobeEvent("tkbtf", symbol.GetProbes()[0])
+	require.NoError(t, err)
+	require.Equal(t, event.String(), migratedEvent.String())
+}
+
+func TestSpec_SymbolFromKprobeEventUserSpace(t *testing.T) {
+	tcs := []struct {
+		name       string
+		event      string
+		tracingStr string
+		err        error
+	}{
+		{
+			name:       "tagged",
+			event:      "p:kprobes/tkbtf_user test_function_user fa3=+u8(%si):u32 fa4=+u0(+u0(%si)):u16",
+			tracingStr: "fa3=+u8(%si):u32 fa4=+u0(+u0(%si)):u16",
+		},
+		{
+			name:  "untagged",
+			event: "p:kprobes/tkbtf_user test_function fi=+u64(+48(%di)):u32",
+			err:   ErrUntaggedUserSpace,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := generateBTFSpec()
+
+			event, err := ParseKprobeEvent(tc.event)
+			require.NoError(t, err)
+
+			symbol, err := spec.SymbolFromKprobeEvent(event)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, spec.BuildSymbol(symbol))
+			require.Equal(t, tc.tracingStr, symbol.GetProbes()[0].GetTracingEventProbe())
+		})
+	}
+}
+
+func TestSpec_SymbolFromKprobeEventUnsupported(t *testing.T) {
+	tcs := []struct {
+		name       string
+		event      string
+		tracingStr string
+		err        error
+	}{
+		{
+			name:  "offset",
+			event: "p:kprobes/tkbtf_offset test_function+16 fi=+64(+48(%di)):u32",
+			err:   ErrInvalidKprobeEvent,
+		},
+		{
+			name:  "max_active",
+			event: "r10:kprobes/tkbtf_max_active test_function ret=$retval:u64",
+			err:   ErrInvalidKprobeEvent,
+		},
+		{
+			name:       "zero_offset",
+			event:      "p:kprobes/tkbtf_offset test_function+0 fi=+64(+48(%di)):u32",
+			tracingStr: "fi=+64(+48(%di)):u32",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := generateBTFSpec()
+
+			event, err := ParseKprobeEvent(tc.event)
+			require.NoError(t, err)
+
+			symbol, err := spec.SymbolFromKprobeEvent(event)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				require.Nil(t, symbol)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, spec.BuildSymbol(symbol))
+			require.Equal(t, tc.tracingStr, symbol.GetProbes()[0].GetTracingEventProbe())
+		})
+	}
+}
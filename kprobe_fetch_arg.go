@@ -0,0 +1,254 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// KprobeFetchArg is the parsed representation of a kprobe fetch arg
+// (https://docs.kernel.org/trace/kprobetrace.html#synopsis-of-kprobe-events), e.g. fi=+64(+48(%di)):u32.
+type KprobeFetchArg struct {
+	// Name is the name of the fetch arg, empty if the fetch arg is not named.
+	Name string
+	// Root is where fetching starts, namely a register, e.g. %di, a tracing fs variable, e.g. $arg1 or $comm,
+	// a symbol, e.g. @jiffies, or an immediate value, e.g. \1234 or \"str".
+	Root string
+	// RootOffset is the offset of a symbol root, e.g. 8 for @jiffies+8.
+	RootOffset int64
+	// Derefs holds the memory dereferences applied to the root, starting from the innermost one.
+	Derefs []KprobeDeref
+	// Type is the fetch arg type, e.g. u32, string or b3@5/32, empty if the fetch arg has no type.
+	Type string
+}
+
+// KprobeDeref is a memory dereference of a kprobe fetch arg, e.g. +8(...) or +u8(...).
+type KprobeDeref struct {
+	Offset int64
+	// UserSpace is true when the dereference reads user-space memory, e.g. +u8(...).
+	UserSpace bool
+}
+
+// ParseKprobeFetchArg parses the given kprobe fetch arg, e.g. fi=+64(+48(%di)):u32. When the fetch arg is malformed,
+// an ErrInvalidKprobeFetchArg error is returned.
+func ParseKprobeFetchArg(fetchArg string) (*KprobeFetchArg, error) {
+	p := &kprobeFetchArgParser{input: fetchArg}
+
+	arg := &KprobeFetchArg{}
+	arg.Name = p.parseName()
+
+	if err := p.parseFetchArg(arg); err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.input) {
+		if p.input[p.pos] != ':' {
+			return nil, p.errorf("unexpected character %q", p.input[p.pos])
+		}
+
+		arg.Type = p.input[p.pos+1:]
+		if arg.Type == "" || strings.ContainsAny(arg.Type, " \t") {
+			return nil, p.errorf("invalid type %q", arg.Type)
+		}
+	}
+
+	return arg, nil
+}
+
+// String returns the kprobe representation of the fetch arg.
+func (a *KprobeFetchArg) String() string {
+	var fetchArg strings.Builder
+
+	if a.Name != "" {
+		fetchArg.WriteString(a.Name)
+		fetchArg.WriteString("=")
+	}
+
+	for i := len(a.Derefs) - 1; i >= 0; i-- {
+		fetchArg.WriteString(fetchArgOffset(a.Derefs[i].Offset, a.Derefs[i].UserSpace))
+	}
+
+	if strings.HasPrefix(a.Root, "@") {
+		fetchArg.WriteString(symbolOffset(a.Root[1:], a.RootOffset))
+	} else {
+		fetchArg.WriteString(a.Root)
+	}
+
+	fetchArg.WriteString(strings.Repeat(")", len(a.Derefs)))
+
+	if a.Type != "" {
+		fetchArg.WriteString(":")
+		fetchArg.WriteString(a.Type)
+	}
+
+	return fetchArg.String()
+}
+
+// kprobeFetchArgParser is a recursive descent parser of kprobe fetch args.
+type kprobeFetchArgParser struct {
+	input string
+	pos   int
+}
+
+func (p *kprobeFetchArgParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("fetch arg %q at column %d: %s: %w", p.input, p.pos+1, fmt.Sprintf(format, args...),
+		ErrInvalidKprobeFetchArg)
+}
+
+// parseName consumes the name of the fetch arg, if there is one.
+func (p *kprobeFetchArgParser) parseName() string {
+	end := 0
+	for end < len(p.input) && isKprobeNameChar(p.input[end]) {
+		end++
+	}
+
+	if end == 0 || end >= len(p.input) || p.input[end] != '=' {
+		return ""
+	}
+
+	p.pos = end + 1
+	return p.input[:end]
+}
+
+// parseFetchArg consumes a dereference, e.g. +8(...), or the root of the fetch arg.
+func (p *kprobeFetchArgParser) parseFetchArg(arg *KprobeFetchArg) error {
+	if p.pos >= len(p.input) {
+		return p.errorf("missing fetch arg")
+	}
+
+	switch p.input[p.pos] {
+	case '+', '-':
+		deref := KprobeDeref{}
+		sign := int64(1)
+		if p.input[p.pos] == '-' {
+			sign = -1
+		}
+		p.pos++
+
+		if p.pos < len(p.input) && p.input[p.pos] == 'u' {
+			deref.UserSpace = true
+			p.pos++
+		}
+
+		offset, err := p.parseNumber()
+		if err != nil {
+			return err
+		}
+		deref.Offset = sign * offset
+
+		if p.pos >= len(p.input) || p.input[p.pos] != '(' {
+			return p.errorf("expected (")
+		}
+		p.pos++
+
+		if err := p.parseFetchArg(arg); err != nil {
+			return err
+		}
+
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return p.errorf("expected )")
+		}
+		p.pos++
+
+		// the inner dereferences are appended first, thus the slice starts from the innermost one
+		arg.Derefs = append(arg.Derefs, deref)
+		return nil
+	case '%', '$':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && isKprobeNameChar(p.input[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start+1 {
+			return p.errorf("missing name of %q", p.input[start])
+		}
+
+		arg.Root = p.input[start:p.pos]
+		return nil
+	case '@':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && (isKprobeNameChar(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		if p.pos == start+1 {
+			return p.errorf("missing symbol name")
+		}
+		arg.Root = p.input[start:p.pos]
+
+		if p.pos < len(p.input) && (p.input[p.pos] == '+' || p.input[p.pos] == '-') {
+			sign := int64(1)
+			if p.input[p.pos] == '-' {
+				sign = -1
+			}
+			p.pos++
+
+			offset, err := p.parseNumber()
+			if err != nil {
+				return err
+			}
+			arg.RootOffset = sign * offset
+		}
+		return nil
+	case '\\':
+		start := p.pos
+		p.pos++
+		if p.pos < len(p.input) && p.input[p.pos] == '"' {
+			end := strings.IndexByte(p.input[p.pos+1:], '"')
+			if end < 0 {
+				return p.errorf("unterminated immediate string")
+			}
+			p.pos += end + 2
+		} else {
+			for p.pos < len(p.input) && p.input[p.pos] != ')' && p.input[p.pos] != ':' {
+				p.pos++
+			}
+			if p.pos == start+1 {
+				return p.errorf("missing immediate value")
+			}
+		}
+
+		arg.Root = p.input[start:p.pos]
+		return nil
+	default:
+		return p.errorf("unexpected character %q", p.input[p.pos])
+	}
+}
+
+// parseNumber consumes a decimal or hexadecimal unsigned number.
+func (p *kprobeFetchArgParser) parseNumber() (int64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && isKprobeNameChar(p.input[p.pos]) {
+		p.pos++
+	}
+
+	number, err := strconv.ParseInt(p.input[start:p.pos], 0, 64)
+	if err != nil || number < 0 {
+		p.pos = start
+		return 0, p.errorf("invalid offset")
+	}
+
+	return number, nil
+}
+
+// isKprobeNameChar returns true if the given character can be part of a fetch arg name, a register or a variable.
+func isKprobeNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
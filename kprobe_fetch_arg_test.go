@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKprobeFetchArg(t *testing.T) {
+	tcs := []struct {
+		name     string
+		fetchArg string
+		expected *KprobeFetchArg
+		err      error
+	}{
+		{
+			name:     "register",
+			fetchArg: "mask=%si:u32",
+			expected: &KprobeFetchArg{Name: "mask", Root: "%si", Type: "u32"},
+		},
+		{
+			name:     "deref_chain",
+			fetchArg: "fi=+64(+48(%di)):u32",
+			expected: &KprobeFetchArg{
+				Name:   "fi",
+				Root:   "%di",
+				Derefs: []KprobeDeref{{Offset: 48}, {Offset: 64}},
+				Type:   "u32",
+			},
+		},
+		{
+			name:     "user_space_and_negative_deref",
+			fetchArg: "fn=+u0(-16(%x0)):ustring",
+			expected: &KprobeFetchArg{
+				Name:   "fn",
+				Root:   "%x0",
+				Derefs: []KprobeDeref{{Offset: -16}, {Offset: 0, UserSpace: true}},
+				Type:   "ustring",
+			},
+		},
+		{
+			name:     "symbol_with_offset",
+			fetchArg: "lvl=@init_pid_ns+8:u32",
+			expected: &KprobeFetchArg{Name: "lvl", Root: "@init_pid_ns", RootOffset: 8, Type: "u32"},
+		},
+		{
+			name:     "unnamed_variable",
+			fetchArg: "$arg2:x64",
+			expected: &KprobeFetchArg{Root: "$arg2", Type: "x64"},
+		},
+		{
+			name:     "immediate_string",
+			fetchArg: `s=\"a:b)":string`,
+			expected: &KprobeFetchArg{Name: "s", Root: `\"a:b)"`, Type: "string"},
+		},
+		{
+			name:     "bitfield_type",
+			fetchArg: "ms=+8(%di):b3@5/32",
+			expected: &KprobeFetchArg{
+				Name:   "ms",
+				Root:   "%di",
+				Derefs: []KprobeDeref{{Offset: 8}},
+				Type:   "b3@5/32",
+			},
+		},
+		{
+			name:     "unbalanced_parentheses",
+			fetchArg: "fi=+64(+48(%di):u32",
+			err:      ErrInvalidKprobeFetchArg,
+		},
+		{
+			name:     "invalid_offset",
+			fetchArg: "fi=+a4(%di):u32",
+			err:      ErrInvalidKprobeFetchArg,
+		},
+		{
+			name:     "missing_root",
+			fetchArg: "fi=+64():u32",
+			err:      ErrInvalidKprobeFetchArg,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fetchArg, err := ParseKprobeFetchArg(tc.fetchArg)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, fetchArg)
+			require.Equal(t, tc.fetchArg, fetchArg.String())
+		})
+	}
+}
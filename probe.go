@@ -43,6 +43,7 @@ type Probe struct {
 
 	fetchArgOrderName []string
 	fetchArgs         map[string]*fetchArg
+	omittedFetchArgs  []string
 
 	tracingEventProbe  string
 	tracingEventFilter string
@@ -115,6 +116,26 @@ func (p *Probe) GetTracingEventFilter() string {
 	return p.tracingEventFilter
 }
 
+// GetOmittedFetchArgs returns the names of the optional fetchArgs that were omitted from the Probe during its last
+// build, because none of their fieldsBuilders built successfully. The names follow the order the fetchArgs were
+// attached.
+func (p *Probe) GetOmittedFetchArgs() []string {
+	return p.omittedFetchArgs
+}
+
+// GetFetchArgsInfo returns how the fetchArgs of the Probe were resolved during its last successful build, in the order
+// they were attached. The omitted optional fetchArgs are not included.
+func (p *Probe) GetFetchArgsInfo() []*FetchArgInfo {
+	var infos []*FetchArgInfo
+	for _, argName := range p.fetchArgOrderName {
+		if arg, ok := p.fetchArgs[argName]; ok && arg.info != nil {
+			infos = append(infos, arg.info)
+		}
+	}
+
+	return infos
+}
+
 // GetType returns the ProbeType.
 func (p *Probe) GetType() ProbeType {
 	return p.probeType
@@ -143,8 +164,9 @@ func (p *Probe) GetID() string {
 }
 
 // build updates the Probe with the provided symbol name and builds one by one the attached fetchArgs, respecting
-// the order they were attached. It returns any error encountered during the build process.
-func (p *Probe) build(symbolName string, spec btfSpec, funcType *btf.Func, regs registersResolver) error {
+// the order they were attached. Optional fetchArgs that fail to build are omitted. It returns any error encountered
+// during the build process.
+func (p *Probe) build(symbolName string, spec btfSpec, opts resolveOptions, funcType *btf.Func, regs registersResolver) error {
 	var probeTracing strings.Builder
 
 	if p.duplicateFetchArgs {
@@ -153,6 +175,7 @@ func (p *Probe) build(symbolName string, spec btfSpec, funcType *btf.Func, regs
 
 	p.symbolName = symbolName
 	p.tracingEventProbe = ""
+	p.omittedFetchArgs = nil
 
 	// Iterate over the fetch args with the order they were added
 	for _, argName := range p.fetchArgOrderName {
@@ -162,8 +185,14 @@ func (p *Probe) build(symbolName string, spec btfSpec, funcType *btf.Func, regs
 		}
 
 		// Build the fetch argument
-		fetchArgTracingStr, err := arg.build(spec, p.probeType, funcType, regs)
+		fetchArgTracingStr, err := arg.build(spec, opts, p.probeType, funcType, regs)
 		if err != nil {
+			if arg.optional {
+				// the fetch arg is nice-to-have, thus omit it from the probe
+				p.omittedFetchArgs = append(p.omittedFetchArgs, argName)
+				continue
+			}
+			setResolutionErrorsProbe(err, symbolName, p.GetID())
 			return err
 		}
 
@@ -26,16 +26,26 @@ import (
 func TestProbes(t *testing.T) {
 	spec := generateBTFSpec()
 
+	inodeTemplate := NewFetchArgsTemplate().
+		AddFetchArg("ino", "u64", "i_ino").
+		AddFetchArg("mode", FetchArgTypeAuto, "i_mode").
+		AddFetchArg("ctime", "u32", "i_ctime").
+		AddFetchArg("ctime", "u32", "__i_ctime").
+		AddFetchArg("count", "u32", "i_count", "counter")
+
 	cases := []struct {
 		name               string
 		symbolNames        []string
 		skipValidation     bool
+		anonymousMembers   bool
+		fieldAliases       [][]string
 		probe              *Probe
 		expectedSymbol     string
 		expectedID         string
 		expectedType       ProbeType
 		expectedTracingStr string
 		expectedFilterStr  string
+		expectedOmitted    []string
 		err                error
 	}{
 		{
@@ -108,6 +118,663 @@ func TestProbes(t *testing.T) {
 			),
 			err: ErrArrayIndexInvalidField,
 		},
+		{
+			name:        "kprobe_auto_type",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+				NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_inode"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncParamWithName("inode_param", "i_mode"),
+				NewFetchArg("fa4", FetchArgTypeAuto).FuncParamWithName("inode_param"),
+				NewFetchArg("fa5", FetchArgTypeAuto).FuncParamArbitrary(1, WrapNone, "inode", "i_ino"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(+48(%di)):u64 fa2=+48(%di):x64 fa3=+0(%si):u16 fa4=%si:x64 fa5=+64(%si):u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_auto_type_fallback",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).
+					FuncParamWithName("dentry_param", "d_name").
+					FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(+48(%di)):u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_auto_type_unsupported",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_name"),
+			),
+			err: ErrUnsupportedAutoType,
+		},
+		{
+			name:        "kretprobe_auto_type",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncReturn(),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kretprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=%ax:x64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_bitfield_member",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_inode", "i_state"),
+				NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("inode_param", "i_state"),
+				NewFetchArg("fa3", BitFieldTypeMask(uint16(0xF0))).FuncParamWithName("inode_param", "i_mode"),
+				NewFetchArg("fa4", "b4@3/32").FuncParamWithName("inode_param", "i_state"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+4(+48(%di)):b5@3/32 fa2=+4(%si):b5@3/32 fa3=+0(%si):b4@4/16 fa4=+4(%si):b4@3/32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_bitfield_member_explicit_type",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_state"),
+			),
+			err: ErrInvalidBitfieldFetchArgType,
+		},
+		{
+			name:        "kprobe_qualifiers_and_typedefs",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_count", "counter"),
+				NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("inode_param", "i_flags"),
+				NewFetchArg("fa3", "u64").FuncParamWithName("dentry_param", "d_fsdata", "i_ino"),
+				NewFetchArg("fa4", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_fsdata"),
+				NewFetchArg("fa5", "s32").FuncParamArbitrary(1, WrapPointer, "atomic_t", "counter"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+128(%si):u32 fa2=+132(%si):u32 fa3=+64(+88(%di)):u64 fa4=+88(%di):x64 fa5=+0(%si):s32",
+			err:                nil,
+		},
+		{
+			name:             "kprobe_anonymous_member_lookup",
+			symbolNames:      []string{"test_function_with_ret"},
+			anonymousMembers: true,
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("tsk_param", "group_leader", "real_parent", "tgid"),
+				NewFetchArg("fa2", "u32").FuncParamWithName("tsk_param", "pid"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncParamWithName("tsk_param", "exit_state"),
+				NewFetchArg("fa4", "u32").FuncParamWithName("tsk_param", "", "numbers", "index:1", "val"),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+1508(+144(+136(%dx))):u32 fa2=+1504(%dx):u32 fa3=+144(%dx):b2@3/32 fa4=+1(+40(+4(%dx))):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_anonymous_member_lookup_disabled",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("tsk_param", "group_leader", "real_parent", "tgid"),
+			),
+			err: ErrFieldNotFound,
+		},
+		{
+			name:        "kprobe_expression",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FromExpression("dentry_param->d_inode->i_ino"),
+				NewFetchArg("fa2", "u64").FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+				NewFetchArg("fa3", "string").FromExpression("$arg2(struct dentry *)->d_name.name"),
+				NewFetchArg("fa4", "string").FuncParamArbitrary(1, WrapPointer, "dentry", "d_name", "name"),
+				NewFetchArg("fa5", "u64").FromExpression("inode_param(struct dentry **)->d_inode->i_ino"),
+				NewFetchArg("fa6", "u64").FuncParamWithCustomType("inode_param", WrapStructPointer, "dentry", "d_inode", "i_ino"),
+				NewFetchArg("fa7", "u32").FromExpression("$arg1(numbers)[ENUM_VAL_2]->val"),
+				NewFetchArg("fa8", "u32").FuncParamArbitrary(0, WrapNone, "numbers", "enum:an_enum:ENUM_VAL_2", "val"),
+				NewFetchArg("fa9", "u32").FromExpression("$arg1(numbers)[1]->val"),
+				NewFetchArg("fa10", "u32").FuncParamArbitrary(0, WrapNone, "numbers", "index:1", "val"),
+				NewFetchArg("fa11", "string").FromExpression("$arg2(struct  dentry*)->d_name.name"),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(+48(%di)):u64 fa2=+64(+48(%di)):u64 fa3=+0(+40(%si)):string fa4=+0(+40(%si)):string fa5=+64(+48(+0(%si))):u64 fa6=+64(+48(+0(%si))):u64 fa7=+1(+16(%di)):u32 fa8=+1(+16(%di)):u32 fa9=+1(+8(%di)):u32 fa10=+1(+8(%di)):u32 fa11=+0(+40(%si)):string",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_expression_user_space",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").FromExpression("dentry_param(const char __user *)"),
+				NewFetchArg("fa2", "u16").FromExpression("$arg2(struct sockaddr __user *)->sa_family"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+0(%di):ustring fa2=+u0(%si):u16",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_expression_fallback",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FromExpression("dentry_param->d_inode->").FromExpression("dentry_param->d_inode->i_ino"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(+48(%di)):u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_expression_invalid",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FromExpression("dentry_param->d_inode->"),
+			),
+			err: ErrInvalidExpression,
+		},
+		{
+			name:        "kretprobe_expression",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FromExpression("$retval->d_inode->i_ino"),
+				NewFetchArg("fa2", "u64").FromExpression("$retval(struct dentry *)->d_inode->i_ino"),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kretprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=+64(+48(%ax)):u64 fa2=+64(+48(%ax)):u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_container_of",
+			symbolNames: []string{"test_function_list"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("node", ContainerOf("task_struct", "tasks"), "pid"),
+				NewFetchArg("fa2", "u32").FromExpression("container_of(node, struct task_struct, tasks)->tgid"),
+				NewFetchArg("fa3", "u32").FromExpression("container_of(node->next, struct task_struct, tasks)->pid"),
+				NewFetchArg("fa4", "u32").FuncParamWithName("node", ContainerOf("task_struct", "tasks"), "", "numbers", "index:1", "val"),
+				NewFetchArg("fa5", "u32").FromExpression("container_of(container_of(node, struct task_struct, tasks)->tasks.prev, struct task_struct, tasks)->pid"),
+			),
+			expectedSymbol:     "test_function_list",
+			expectedID:         "kprobe_test_function_list",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+1248(%di):u32 fa2=+1252(%di):u32 fa3=+1248(+0(%di)):u32 fa4=+1(+40(-252(%di))):u32 fa5=+1248(+8(%di)):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_container_of_member_mismatch",
+			symbolNames: []string{"test_function_list"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("node", ContainerOf("dentry", "d_name"), "d_inode"),
+			),
+			err: ErrInvalidContainerOf,
+		},
+		{
+			name:        "kprobe_container_of_missing_member",
+			symbolNames: []string{"test_function_list"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("node", ContainerOf("task_struct", "children"), "pid"),
+			),
+			err: ErrFieldNotFound,
+		},
+		{
+			name:        "kprobe_container_of_last_field",
+			symbolNames: []string{"test_function_list"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("node", ContainerOf("task_struct", "tasks")),
+			),
+			err: ErrInvalidContainerOf,
+		},
+		{
+			name:        "kprobe_field_alias",
+			symbolNames: []string{"test_function"},
+			fieldAliases: [][]string{
+				{"inode", "i_ctime", "i_ctime_sec", "__i_ctime"},
+				{"gid_t", "val", ""},
+			},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_ctime"),
+				NewFetchArg("fa2", "u32").FuncParamWithName("inode_param", "__i_ctime"),
+				NewFetchArg("fa3", "u32").FuncParamWithName("inode_param", "i_ino"),
+				NewFetchArg("fa4", FetchArgTypeAuto).FuncParamArbitrary(0, WrapPointer, "cred", "gid", "val"),
+				NewFetchArg("fa5", FetchArgTypeAuto).FuncParamArbitrary(0, WrapPointer, "cred", "egid", "val"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+136(%si):u32 fa2=+136(%si):u32 fa3=+64(%si):u32 fa4=+8(%di):u32 fa5=+12(%di):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_field_alias_typedef",
+			symbolNames: []string{"test_function"},
+			fieldAliases: [][]string{
+				{"__kernel_gid32_t", "val", ""},
+			},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamArbitrary(0, WrapPointer, "cred", "gid", "val"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+8(%di):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_field_alias_missing",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_ctime"),
+			),
+			err: ErrFieldNotFound,
+		},
+		{
+			name:        "kprobe_field_alias_value_missing",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamArbitrary(0, WrapPointer, "cred", "gid", "val"),
+			),
+			err: ErrFieldNotFound,
+		},
+		{
+			name:        "kprobe_optional_fetch_args",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_ino"),
+				NewFetchArg("fa2", "u32").SetOptional(true).FuncParamWithName("inode_param", "i_ctime"),
+				NewFetchArg("fa3", "u32").SetOptional(true).FuncParamWithName("inode_param", "__i_ctime"),
+				NewFetchArg("fa4", "u64").SetOptional(true).FuncReturnValue(),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(%si):u32 fa3=+136(%si):u32",
+			expectedOmitted:    []string{"fa2", "fa4"},
+			err:                nil,
+		},
+		{
+			name:        "kprobe_optional_fetch_args_disabled",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").SetOptional(false).FuncParamWithName("inode_param", "i_ctime"),
+			),
+			err: ErrFieldNotFound,
+		},
+		{
+			name:        "kprobe_optional_fetch_args_template",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArgsTemplate().
+					AddFetchArg("ino", "u32", "i_ino").
+					AddOptionalFetchArg("ctime", "u32", "i_ctime").
+					Instantiate("f", NewTemplateBase().FuncParamWithName("inode_param"))...,
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fino=+64(%si):u32",
+			expectedOmitted:    []string{"fctime"},
+			err:                nil,
+		},
+		{
+			name:        "kprobe_fetch_args_template",
+			symbolNames: []string{"test_function_inodes"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("mask", "u32").FuncParamWithName("mask"),
+			).AddFetchArgs(
+				inodeTemplate.Instantiate("d", NewTemplateBase().FuncParamWithName("to_tell").FuncParamWithName("dir"))...,
+			).AddFetchArgs(
+				inodeTemplate.Instantiate("", NewTemplateBase().FuncParamWithType("struct inode *", 1))...,
+			),
+			expectedSymbol:     "test_function_inodes",
+			expectedID:         "kprobe_test_function_inodes",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "mask=%di:u32 dino=+64(%si):u64 dmode=+0(%si):u16 dctime=+136(%si):u32 dcount=+128(%si):u32 ino=+64(%dx):u64 mode=+0(%dx):u16 ctime=+136(%dx):u32 count=+128(%dx):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_fetch_args_template_missing_base",
+			symbolNames: []string{"test_function_inodes"},
+			probe: NewKProbe().AddFetchArgs(
+				inodeTemplate.Instantiate("", nil)...,
+			),
+			err: ErrMissingFieldBuilders,
+		},
+		{
+			name:        "kprobe_func_param_with_type",
+			symbolNames: []string{"test_function_inodes"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncParamWithType("struct inode *", 0, "i_ino"),
+				NewFetchArg("fa2", "u64").FuncParamWithType("struct inode*", 1, "i_ino"),
+				NewFetchArg("fa3", "u32").FuncParamWithType("unsigned int", 0),
+				NewFetchArg("fa4", "u64").FuncParamWithType("struct inode *", 2, "i_ino").FuncParamWithName("inode", "i_ino"),
+				NewFetchArg("fa5", "u64").FuncParamWithType("const struct inode *", 0, "i_ino"),
+			),
+			expectedSymbol:     "test_function_inodes",
+			expectedID:         "kprobe_test_function_inodes",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+64(%si):u64 fa2=+64(%dx):u64 fa3=%di:u32 fa4=+64(%dx):u64 fa5=+64(%si):u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_func_param_with_type_not_found",
+			symbolNames: []string{"test_function_inodes"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncParamWithType("struct inode *", 2, "i_ino"),
+			),
+			err: ErrFuncParamNotFound,
+		},
+		{
+			name:        "kretprobe_func_param_with_type",
+			symbolNames: []string{"test_function_inodes"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncParamWithType("struct inode *", 0, "i_ino"),
+			),
+			err: ErrIncompatibleFetchArg,
+		},
+		{
+			name:        "kretprobe_func_return_with_type",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncReturnWithType("struct inode *", "i_ino").FuncReturnWithType("struct dentry *", "d_inode", "i_ino"),
+				NewFetchArg("fa2", "x64").FuncReturnWithType("struct dentry*"),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kretprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=+64(+48(%ax)):u64 fa2=%ax:x64",
+			err:                nil,
+		},
+		{
+			name:        "kretprobe_func_return_with_type_mismatch",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncReturnWithType("struct inode *", "i_ino"),
+			),
+			err: ErrFuncParamNotFound,
+		},
+		{
+			name:        "kprobe_func_pointer",
+			symbolNames: []string{"test_function_file"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("file", "f_op", "read_iter"),
+				NewFetchArg("fa2", "string").FuncParamWithName("file", "f_op", "read_iter"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FromExpression("file->f_op->llseek"),
+				NewFetchArg("fa4", "x64").FuncParamWithName("file", "f_op", "read_iter"),
+				NewFetchArg("fa5", FetchArgTypeAuto).FuncParamArbitrary(1, WrapPointer, "test_function"),
+				NewFetchArg("fa6", FetchArgTypeAuto).FuncParamArbitrary(0, WrapNone, "file_operations", "read_iter"),
+				NewFetchArg("fa7", FetchArgTypeAuto).FuncParamValue(0),
+			),
+			expectedSymbol:     "test_function_file",
+			expectedID:         "kprobe_test_function_file",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+8(+40(%di)):symbol fa2=+8(+40(%di)):symstr fa3=+0(+40(%di)):symbol fa4=+8(+40(%di)):x64 fa5=%si:symbol fa6=+8(%di):symbol fa7=$arg1:x64",
+			err:                nil,
+		},
+		{
+			name:        "kretprobe_func_pointer",
+			symbolNames: []string{"test_function_file"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncReturn(),
+				NewFetchArg("fa2", "string").FuncReturnArbitrary(WrapPointer, "test_function"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncReturnValue(),
+			),
+			expectedSymbol:     "test_function_file",
+			expectedID:         "kretprobe_test_function_file",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=%ax:symbol fa2=%ax:symstr fa3=$retval:symbol",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_fetch_variables",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").Comm(),
+				NewFetchArg("fa2", FetchArgTypeAuto).Comm(),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncParamValue(1),
+				NewFetchArg("fa4", FetchArgTypeAuto).FuncParamValue(4),
+				NewFetchArg("fa5", "u64").Stack(),
+				NewFetchArg("fa6", "u64").StackEntry(2),
+				NewFetchArg("fa7", FetchArgTypeAuto).Immediate(-42),
+				NewFetchArg("fa8", "string").ImmediateString("tk-btf"),
+				NewFetchArg("fa9", "u32").FuncParamWithName("inode_param", "i_ino"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=$comm:string fa2=$comm:string fa3=$arg2:x64 fa4=$arg5:x64 fa5=$stack:u64 fa6=$stack2:u64 fa7=\\-42:s64 fa8=\\\"tk-btf\":string fa9=+64(%si):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_fetch_variables_user_space",
+			symbolNames: []string{"test_function_user"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncParamValue(0),
+			),
+			expectedSymbol:     "test_function_user",
+			expectedID:         "kprobe_test_function_user",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+0($arg1):ustring",
+			err:                nil,
+		},
+		{
+			name:        "kretprobe_fetch_variables",
+			symbolNames: []string{"test_function"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).FuncReturnValue(),
+				NewFetchArg("fa2", "string").Comm(),
+				NewFetchArg("fa3", "u16").FuncReturn(),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kretprobe_test_function",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=$retval:u16 fa2=$comm:string fa3=%ax:u16",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_fetch_variable_retval_incompatible",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncReturnValue(),
+			),
+			err: ErrIncompatibleFetchArg,
+		},
+		{
+			name:        "kretprobe_fetch_variable_arg_incompatible",
+			symbolNames: []string{"test_function"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncParamValue(0),
+			),
+			err: ErrIncompatibleFetchArg,
+		},
+		{
+			name:        "kprobe_fetch_variable_invalid",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").StackEntry(-1),
+			),
+			err: ErrInvalidFetchVariable,
+		},
+		{
+			name:        "kprobe_fetch_variable_invalid_immediate_string",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").ImmediateString("tk btf"),
+			),
+			err: ErrInvalidFetchVariable,
+		},
+		{
+			name:        "kprobe_fetch_variable_invalid_param_index",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").FuncParamValue(-1),
+			),
+			err: ErrUnsupportedFuncParamIndex,
+		},
+		{
+			name:        "kprobe_fetch_variables_without_validation",
+			symbolNames: []string{"missing_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").Comm(),
+				NewFetchArg("fa2", FetchArgTypeAuto).FuncParamValue(1),
+			),
+			skipValidation:     true,
+			expectedSymbol:     "missing_function",
+			expectedID:         "kprobe_missing_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=$comm:string fa2=$arg2:x64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_global_variable",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", FetchArgTypeAuto).GlobalVariable("jiffies"),
+				NewFetchArg("fa2", "u32").GlobalVariable("init_pid_ns", "level"),
+				NewFetchArg("fa3", "u32").GlobalVariable("init_pid_ns", "child_reaper", "pid"),
+				NewFetchArg("fa4", "string").GlobalVariable("saved_command_line"),
+				NewFetchArg("fa5", FetchArgTypeAuto).GlobalVariable("linux_banner"),
+				NewFetchArg("fa6", "u32").FromExpression("@init_pid_ns.child_reaper->tgid"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=@jiffies:u64 fa2=@init_pid_ns+8:u32 fa3=+1504(@init_pid_ns+16):u32 fa4=+0(@saved_command_line):string fa5=@linux_banner:string fa6=+1508(@init_pid_ns+16):u32",
+			err:                nil,
+		},
+		{
+			name:        "kretprobe_global_variable",
+			symbolNames: []string{"test_function"},
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u64").GlobalVariable("jiffies"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kretprobe_test_function",
+			expectedType:       ProbeTypeKRetProbe,
+			expectedTracingStr: "fa1=@jiffies:u64",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_global_variable_per_cpu",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").GlobalVariable("runqueues", "level"),
+			),
+			err: ErrUnsupportedGlobalVariable,
+		},
+		{
+			name:        "kprobe_global_variable_not_found",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32").GlobalVariable("nr_threads"),
+			),
+			err: ErrGlobalVariableNotFound,
+		},
+		{
+			name:        "kprobe_embedded_char_array",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").FuncParamWithName("dentry_param", "d_iname"),
+				NewFetchArg("fa2", "u8[32]").FuncParamWithName("dentry_param", "d_iname"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncParamWithName("dentry_param", "d_iname"),
+				NewFetchArg("fa4", "u8[8]").FuncParamWithName("dentry_param", "d_name", "name"),
+				NewFetchArg("fa5", "string").FuncParamArbitrary(0, WrapStructPointer, "dentry", "d_iname"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+56(%di):string fa2=+56(%di):u8[32] fa3=+56(%di):string fa4=+0(+40(%di)):u8[8] fa5=+56(+0(%di)):string",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_array_type",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "x64[4]").FuncParamWithName("tsk_param", "", "numbers"),
+				NewFetchArg("fa2", "x64[2]").FuncParamWithName("tsk_param", "", "numbers"),
+				NewFetchArg("fa3", FetchArgTypeAuto).FuncParamWithName("tsk_param", "", "numbers"),
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+32(+4(%dx)):x64[4] fa2=+32(+4(%dx)):x64[2] fa3=+32(+4(%dx)):x64[4]",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_array_type_exceeds_elements",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "x64[5]").FuncParamWithName("tsk_param", "", "numbers"),
+			),
+			err: ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:        "kprobe_array_type_scalar",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "u32[4]").FuncParamWithName("inode_param", "i_ino"),
+			),
+			err: ErrInvalidArrayFetchArgType,
+		},
+		{
+			name:        "kprobe_array_elements_expansion",
+			symbolNames: []string{"test_function_with_ret"},
+			probe: NewKProbe().AddFetchArgs(
+				NewArrayFetchArgs("nr", "u32", 3, func(f *fetchArg, index string) *fetchArg {
+					return f.FuncParamWithName("tsk_param", "", "numbers", index, "val")
+				})...,
+			),
+			expectedSymbol:     "test_function_with_ret",
+			expectedID:         "kprobe_test_function_with_ret",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "nr0=+1(+32(+4(%dx))):u32 nr1=+1(+40(+4(%dx))):u32 nr2=+1(+48(+4(%dx))):u32",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_user_space",
+			symbolNames: []string{"test_function_user"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").FuncParamWithName("filename"),
+				NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("filename"),
+				NewFetchArg("fa3", "u32").FuncParamWithName("msg", "msg_namelen"),
+				NewFetchArg("fa4", "u16").FuncParamWithName("msg", "msg_name", "sa_family"),
+				NewFetchArg("fa5", "string").FuncParamWithName("msg", "msg_name", "sa_data"),
+				NewFetchArg("fa6", "u8[4]").FuncParamWithName("filename"),
+			),
+			expectedSymbol:     "test_function_user",
+			expectedID:         "kprobe_test_function_user",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+0(%di):ustring fa2=+0(%di):ustring fa3=+u8(%si):u32 fa4=+u0(+u0(%si)):u16 fa5=+u2(+u0(%si)):ustring fa6=+u0(%di):u8[4]",
+			err:                nil,
+		},
+		{
+			name:        "kprobe_user_space_override",
+			symbolNames: []string{"test_function"},
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fa1", "string").FuncParamWithCustomType("dentry_param", WrapUserPointer, "char"),
+				NewFetchArg("fa2", "u16").FuncParamArbitrary(1, WrapUserPointer, "sockaddr", "sa_family"),
+				NewFetchArg("fa3", "ustring").FuncParamWithName("dentry_param", "d_name", "name"),
+			),
+			expectedSymbol:     "test_function",
+			expectedID:         "kprobe_test_function",
+			expectedType:       ProbeTypeKProbe,
+			expectedTracingStr: "fa1=+0(%di):ustring fa2=+u0(%si):u16 fa3=+0(+40(%di)):ustring",
+			err:                nil,
+		},
 		{
 			name:        "kprobe_duplicate_fetch_arg",
 			symbolNames: []string{"test_function"},
@@ -398,6 +1065,11 @@ func TestProbes(t *testing.T) {
 				symbol = NewSymbol(c.symbolNames...).AddProbes(c.probe)
 			}
 
+			spec.SetAnonymousMemberLookup(c.anonymousMembers)
+			spec.opts.fieldAliases = nil
+			for _, alias := range c.fieldAliases {
+				spec.AddFieldAlias(alias[0], alias[1], alias[2:]...)
+			}
 			err := spec.BuildSymbol(symbol)
 			require.ErrorIs(t, err, c.err)
 
@@ -407,6 +1079,7 @@ func TestProbes(t *testing.T) {
 
 			require.Equal(t, c.expectedTracingStr, c.probe.GetTracingEventProbe())
 			require.Equal(t, c.expectedFilterStr, c.probe.GetTracingEventFilter())
+			require.Equal(t, c.expectedOmitted, c.probe.GetOmittedFetchArgs())
 			require.Equal(t, c.expectedSymbol, c.probe.GetSymbolName())
 			require.Equal(t, c.expectedType, c.probe.GetType())
 			require.Equal(t, c.expectedID, c.probe.GetID())
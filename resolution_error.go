@@ -0,0 +1,185 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldResolutionError describes a field path of a fetch arg that failed to resolve. Path holds all the fields
+// of the fieldsBuilder and Position is the index in Path of the field where resolution stopped. Symbol and ProbeID
+// are set when the fetch arg is built as part of a Probe. Spec is set when the Symbol is built by a Spec and
+// identifies the btf the lookup failed against, i.e. "kernel" or the path of the btf file. A Spec generated from a
+// reader has no identifier, thus callers should wrap the error with their own description of it. Err holds the
+// underlying error, thus errors.Is matches the respective sentinel error, e.g. ErrFieldNotFound.
+type FieldResolutionError struct {
+	Spec         string
+	Symbol       string
+	ProbeID      string
+	FetchArg     string
+	BuilderIndex int
+	Path         []string
+	Position     int
+	Err          error
+}
+
+func (e *FieldResolutionError) Error() string {
+	return fmt.Sprintf("%sresolving field %s at position %d of path %s failed: %v",
+		resolutionErrorPrefix(e.Spec, e.Symbol, e.ProbeID, e.FetchArg, e.BuilderIndex), e.Path[e.Position], e.Position,
+		strings.Join(e.Path, "."), e.Err)
+}
+
+// Unwrap returns the underlying error of the FieldResolutionError.
+func (e *FieldResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// ParamResolutionError describes a function parameter, return value or global variable of a fetch arg that failed
+// to resolve. Param is a description of the searched value, e.g. the parameter name, "struct inode *#1" for the
+// second parameter of type struct inode *, or $retval. Symbol, ProbeID and Spec are set as in FieldResolutionError.
+// Err holds the underlying error, thus errors.Is matches the respective sentinel error, e.g. ErrFuncParamNotFound.
+type ParamResolutionError struct {
+	Spec         string
+	Symbol       string
+	ProbeID      string
+	FetchArg     string
+	BuilderIndex int
+	Param        string
+	Err          error
+}
+
+func (e *ParamResolutionError) Error() string {
+	return fmt.Sprintf("%sresolving %s failed: %v",
+		resolutionErrorPrefix(e.Spec, e.Symbol, e.ProbeID, e.FetchArg, e.BuilderIndex), e.Param, e.Err)
+}
+
+// Unwrap returns the underlying error of the ParamResolutionError.
+func (e *ParamResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// resolutionErrorPrefix returns the common prefix of the resolution errors that points to the failed fieldsBuilder.
+func resolutionErrorPrefix(spec string, symbol string, probeID string, fetchArg string, builderIndex int) string {
+	var prefix strings.Builder
+
+	if spec != "" {
+		prefix.WriteString(fmt.Sprintf("spec %s ", spec))
+	}
+	if symbol != "" {
+		prefix.WriteString(fmt.Sprintf("symbol %s probe %s ", symbol, probeID))
+	}
+	prefix.WriteString(fmt.Sprintf("fetch arg %s builder %d: ", fetchArg, builderIndex))
+
+	return prefix.String()
+}
+
+// newResolutionError wraps the error of the fieldsBuilder at the given index of the fetch arg in a
+// ParamResolutionError, when the function parameter, return value or global variable is not found, or in a
+// FieldResolutionError, when resolution stopped at one of its fields. Any other error is returned as is.
+func newResolutionError(fetchArg string, builderIndex int, p fieldsBuilder, err error) error {
+	if errors.Is(err, ErrFuncParamNotFound) || errors.Is(err, ErrGlobalVariableNotFound) {
+		return &ParamResolutionError{
+			FetchArg:     fetchArg,
+			BuilderIndex: builderIndex,
+			Param:        describeParam(p),
+			Err:          err,
+		}
+	}
+
+	fields := p.getFields()
+	for i, fld := range fields {
+		if fld.seen {
+			continue
+		}
+
+		path := make([]string, len(fields))
+		for j, pathField := range fields {
+			path[j] = pathField.name
+		}
+
+		return &FieldResolutionError{
+			FetchArg:     fetchArg,
+			BuilderIndex: builderIndex,
+			Path:         path,
+			Position:     i,
+			Err:          err,
+		}
+	}
+
+	return err
+}
+
+// describeParam returns a description of the value that the given fieldsBuilder resolves its fields from.
+func describeParam(p fieldsBuilder) string {
+	switch t := p.(type) {
+	case *funcParamWithName:
+		return t.name
+	case *funcParamWithType:
+		return fmt.Sprintf("%s#%d", t.typeName, t.occurrence)
+	case *funcParamArbitrary:
+		return t.name
+	case *funcParamAtIndex:
+		return fmt.Sprintf("$arg%d", t.index+1)
+	case *funcReturnWithType:
+		return fmt.Sprintf("$retval of type %s", t.typeName)
+	case *funcReturn, *funcReturnArbitrary:
+		return "$retval"
+	case *globalVariable:
+		return "@" + t.name
+	case *fetchVariable:
+		return t.variable
+	default:
+		return fmt.Sprintf("%T", p)
+	}
+}
+
+// setResolutionErrorsProbe sets the symbol and the probe ID to all resolution errors of the given error tree.
+func setResolutionErrorsProbe(err error, symbol string, probeID string) {
+	switch e := err.(type) {
+	case *FieldResolutionError:
+		e.Symbol = symbol
+		e.ProbeID = probeID
+	case *ParamResolutionError:
+		e.Symbol = symbol
+		e.ProbeID = probeID
+	case interface{ Unwrap() []error }:
+		for _, joined := range e.Unwrap() {
+			setResolutionErrorsProbe(joined, symbol, probeID)
+		}
+	case interface{ Unwrap() error }:
+		setResolutionErrorsProbe(e.Unwrap(), symbol, probeID)
+	}
+}
+
+// setResolutionErrorsSpec sets the identifier of the spec to all resolution errors of the given error tree.
+func setResolutionErrorsSpec(err error, spec string) {
+	switch e := err.(type) {
+	case *FieldResolutionError:
+		e.Spec = spec
+	case *ParamResolutionError:
+		e.Spec = spec
+	case interface{ Unwrap() []error }:
+		for _, joined := range e.Unwrap() {
+			setResolutionErrorsSpec(joined, spec)
+		}
+	case interface{ Unwrap() error }:
+		setResolutionErrorsSpec(e.Unwrap(), spec)
+	}
+}
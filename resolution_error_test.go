@@ -0,0 +1,124 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolutionErrors(t *testing.T) {
+	spec := generateBTFSpec()
+
+	t.Run("field_resolution_error", func(t *testing.T) {
+		symbol := NewSymbol("test_function").AddProbes(
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("fi", "u32").
+					FuncParamWithName("unknown_param", "i_ino").
+					FuncParamWithName("dentry_param", "d_inode", "i_unknown"),
+			),
+		)
+
+		err := spec.BuildSymbol(symbol)
+		require.ErrorIs(t, err, ErrFieldNotFound)
+		require.ErrorIs(t, err, ErrFuncParamNotFound)
+
+		var fieldErr *FieldResolutionError
+		require.True(t, errors.As(err, &fieldErr))
+		require.Empty(t, fieldErr.Spec)
+		require.Equal(t, "test_function", fieldErr.Symbol)
+		require.Equal(t, "kprobe_test_function", fieldErr.ProbeID)
+		require.Equal(t, "fi", fieldErr.FetchArg)
+		require.Equal(t, 1, fieldErr.BuilderIndex)
+		require.Equal(t, []string{"d_inode", "i_unknown"}, fieldErr.Path)
+		require.Equal(t, 1, fieldErr.Position)
+
+		var paramErr *ParamResolutionError
+		require.True(t, errors.As(err, &paramErr))
+		require.Equal(t, "test_function", paramErr.Symbol)
+		require.Equal(t, "kprobe_test_function", paramErr.ProbeID)
+		require.Equal(t, "fi", paramErr.FetchArg)
+		require.Equal(t, 0, paramErr.BuilderIndex)
+		require.Equal(t, "unknown_param", paramErr.Param)
+	})
+
+	t.Run("position_after_previous_build", func(t *testing.T) {
+		fetchArg := NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_ino")
+		symbol := NewSymbol("test_function").AddProbes(NewKProbe().AddFetchArgs(fetchArg))
+		require.NoError(t, spec.BuildSymbol(symbol))
+
+		// the fields resolved during the previous build must not hide where the resolution stops
+		fetchArg.fBuilders[0].getFields()[1].name = "i_unknown"
+
+		err := spec.BuildSymbol(symbol)
+		var fieldErr *FieldResolutionError
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, 1, fieldErr.Position)
+		require.Equal(t, []string{"d_inode", "i_unknown"}, fieldErr.Path)
+	})
+
+	t.Run("spec_from_path", func(t *testing.T) {
+		symbol := NewSymbol("test_function").AddProbes(
+			NewKProbe().AddFetchArgs(NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_ino")),
+		)
+		require.NoError(t, spec.BuildSymbol(symbol))
+
+		fileName := filepath.Join(t.TempDir(), "btfFile")
+		require.NoError(t, spec.StripAndSave(fileName, symbol))
+
+		pathSpec, err := NewSpecFromPath(fileName, &SpecOptions{
+			arch: "amd64",
+		})
+		require.NoError(t, err)
+
+		err = pathSpec.BuildSymbol(NewSymbol("test_function").AddProbes(
+			NewKProbe().AddFetchArgs(NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_unknown")),
+		))
+		require.ErrorIs(t, err, ErrFieldNotFound)
+		require.ErrorContains(t, err, "spec "+fileName+" symbol test_function")
+
+		var fieldErr *FieldResolutionError
+		require.True(t, errors.As(err, &fieldErr))
+		require.Equal(t, fileName, fieldErr.Spec)
+
+		err = pathSpec.BuildSymbol(NewSymbol("test_function").AddProbes(
+			NewKProbe().AddFetchArgs(NewFetchArg("fi", "u32").FuncParamWithName("unknown_param", "i_ino")),
+		))
+		var paramErr *ParamResolutionError
+		require.True(t, errors.As(err, &paramErr))
+		require.Equal(t, fileName, paramErr.Spec)
+	})
+
+	t.Run("fetch_arg_without_probe", func(t *testing.T) {
+		var funcType *btf.Func
+		require.NoError(t, spec.spec.TypeByName("test_function", &funcType))
+
+		fetchArg := NewFetchArg("ret", "u32").FuncReturnWithType("struct inode *")
+		_, err := fetchArg.build(spec.spec, spec.opts, ProbeTypeKRetProbe, funcType, spec.regs)
+		require.ErrorIs(t, err, ErrFuncParamNotFound)
+
+		var paramErr *ParamResolutionError
+		require.True(t, errors.As(err, &paramErr))
+		require.Empty(t, paramErr.Symbol)
+		require.Equal(t, "$retval of type struct inode *", paramErr.Param)
+	})
+}
@@ -19,9 +19,11 @@ package tkbtf
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"runtime"
+	"strings"
 
 	"github.com/cilium/ebpf/btf"
 )
@@ -30,6 +32,8 @@ import (
 type btfSpec interface {
 	TypeByName(name string, typ interface{}) error
 	AnyTypesByName(name string) ([]btf.Type, error)
+	enumByValueName(valueName string) (*btf.Enum, error)
+	datasecOfVar(v *btf.Var) (*btf.Datasec, error)
 
 	copy() btfSpec
 	typeID(t btf.Type) (btf.TypeID, error)
@@ -39,10 +43,22 @@ type SpecOptions struct {
 	arch string
 }
 
-// Spec holds the btfSpec and the registersResolver.
+// Spec holds the btfSpec, the registersResolver and the options that affect how fields are resolved. The source
+// identifies where the btfSpec was loaded from, i.e. "kernel" or the file path, and is empty for a btfSpec loaded
+// from a reader.
 type Spec struct {
-	spec btfSpec
-	regs registersResolver
+	spec   btfSpec
+	regs   registersResolver
+	opts   resolveOptions
+	source string
+}
+
+// resolveOptions holds the Spec options that affect how fields are resolved.
+type resolveOptions struct {
+	// anonymousMembers enables the lookup of fields inside anonymous struct and union members.
+	anonymousMembers bool
+	// fieldAliases holds the aliases of the fields keyed by type name and field name, see Spec.AddFieldAlias.
+	fieldAliases map[string][]string
 }
 
 // btfSpecWrapper is a thin wrapper around btf.Spec to implement the btfSpec interface.
@@ -60,7 +76,7 @@ func NewSpecFromKernel() (*Spec, error) {
 		return nil, err
 	}
 
-	return specFromBTF(spec, runtime.GOARCH)
+	return specFromBTF(spec, runtime.GOARCH, "kernel")
 }
 
 // NewSpecFromReader generates a new Spec from the given io.ReaderAt.
@@ -77,7 +93,7 @@ func NewSpecFromReader(rd io.ReaderAt, opts *SpecOptions) (*Spec, error) {
 		arch = runtime.GOARCH
 	}
 
-	return specFromBTF(spec, arch)
+	return specFromBTF(spec, arch, "")
 }
 
 // NewSpecFromPath generates a new Spec from the given file path.
@@ -94,18 +110,19 @@ func NewSpecFromPath(path string, opts *SpecOptions) (*Spec, error) {
 		arch = runtime.GOARCH
 	}
 
-	return specFromBTF(spec, arch)
+	return specFromBTF(spec, arch, path)
 }
 
-func specFromBTF(spec *btf.Spec, arch string) (*Spec, error) {
+func specFromBTF(spec *btf.Spec, arch string, source string) (*Spec, error) {
 	regs, err := getRegistersResolver(arch)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Spec{
-		spec: &btfSpecWrapper{spec: spec},
-		regs: regs,
+		spec:   &btfSpecWrapper{spec: spec},
+		regs:   regs,
+		source: source,
 	}, nil
 }
 
@@ -127,8 +144,20 @@ func (s *Spec) StripAndSave(pathToSave string, symbolsToInclude ...*Symbol) erro
 							continue
 						}
 
+						// keep the anonymous members that were traversed to reach the field
+						for _, anonymous := range paramField.anonymousMembers {
+							if err := typesToKeep.addTypeAnonymousMember(s.spec, anonymous.parent, anonymous.typ); err != nil {
+								return err
+							}
+						}
+
 						if paramField.parentBtfType != nil {
-							if err := typesToKeep.addTypeField(s.spec, paramField.parentBtfType, paramField.name); err != nil {
+							memberName := paramField.name
+							if paramField.memberName != "" {
+								memberName = paramField.memberName
+							}
+
+							if err := typesToKeep.addTypeField(s.spec, paramField.parentBtfType, memberName); err != nil {
 								return err
 							}
 						}
@@ -137,6 +166,25 @@ func (s *Spec) StripAndSave(pathToSave string, symbolsToInclude ...*Symbol) erro
 							return err
 						}
 					}
+
+					// keep the leaf type so that a re-built fetch arg infers the same type
+					if leafType := builtParam.getLeafType(); leafType != nil {
+						if err := typesToKeep.addType(s.spec, leafType); err != nil {
+							return err
+						}
+					}
+				}
+
+				if global, ok := fArg.successfulBuilder.(*globalVariable); ok && global.btfVar != nil {
+					if err := typesToKeep.addType(s.spec, global.btfVar); err != nil {
+						return err
+					}
+
+					if global.datasec != nil {
+						if err := typesToKeep.addType(s.spec, global.datasec); err != nil {
+							return err
+						}
+					}
 				}
 
 				if fArg.btfFunc != nil {
@@ -180,18 +228,70 @@ func (s *Spec) StripAndSave(pathToSave string, symbolsToInclude ...*Symbol) erro
 	return file.Close()
 }
 
+// SetAnonymousMemberLookup enables or disables the automatic lookup of fields inside anonymous struct and union
+// members. When enabled, a field that is not a direct member of a struct or union is searched recursively in its
+// anonymous struct and union members and the offsets of the latter are accumulated. As a result, a single field
+// path, e.g. "p", "group_leader", "real_parent", "tgid", resolves against kernels with and without anonymous
+// member wrappers (e.g. randomized struct layouts). Direct members always take precedence.
+func (s *Spec) SetAnonymousMemberLookup(enabled bool) {
+	s.opts.anonymousMembers = enabled
+}
+
+// AddFieldAlias registers aliases for the field of the struct or union with the given type name, e.g.
+// AddFieldAlias("inode", "i_ctime", "__i_ctime"). When the field is not a member of the type, the aliases are tried
+// in the order they were added and the first one that is a member resolves the field. As a result, a single field
+// path resolves against kernels where the member was renamed, and StripAndSave keeps the member that was actually
+// resolved. An empty alias denotes that the field is the value of the type itself, which unwraps fields that don't
+// exist in older kernels, e.g. AddFieldAlias("uid_t", "val", "") resolves "uid", "val" of a cred when uid is a
+// uid_t instead of a kuid_t; in this case the type name can also be any typedef wrapping the value.
+func (s *Spec) AddFieldAlias(typeName string, fieldName string, aliases ...string) {
+	if s.opts.fieldAliases == nil {
+		s.opts.fieldAliases = make(map[string][]string)
+	}
+
+	key := fieldAliasKey(typeName, fieldName)
+	s.opts.fieldAliases[key] = append(s.opts.fieldAliases[key], aliases...)
+}
+
 // BuildSymbol builds the given symbol against the btf spec.
 func (s *Spec) BuildSymbol(symbol *Symbol) error {
 
 	// Call the build function on the symbol with the first spec
-	if err := symbol.build(s.spec, s.regs); err != nil {
+	if err := symbol.build(s.spec, s.opts, s.regs); err != nil {
 		// If an error occurs, return the error immediately
+		setResolutionErrorsSpec(err, s.source)
 		return err
 	}
 
 	return nil
 }
 
+// BuildSymbols builds the given symbols against the btf spec, respecting the order they were given. Contrary to
+// BuildSymbol, it doesn't stop at the first symbol that fails to build. The returned SymbolsBuildResult holds the
+// symbols that were built and the optional symbols that were skipped, see Symbol.SetOptional. The returned error
+// joins the errors of the required symbols that failed to build, and it is nil when all of them were built.
+func (s *Spec) BuildSymbols(symbols ...*Symbol) (*SymbolsBuildResult, error) {
+	result := &SymbolsBuildResult{}
+
+	var allErr error
+	for _, symbol := range symbols {
+		if err := s.BuildSymbol(symbol); err != nil {
+			if symbol.optional {
+				result.skipped = append(result.skipped, symbol)
+				result.skippedErrs = append(result.skippedErrs, err)
+				continue
+			}
+
+			allErr = errors.Join(allErr, fmt.Errorf("building symbol %s failed: %w", strings.Join(symbol.names, "|"), err))
+			continue
+		}
+
+		result.built = append(result.built, symbol)
+	}
+
+	return result, allErr
+}
+
 // ContainsSymbol returns true if the btf spec contains the given symbol name
 func (s *Spec) ContainsSymbol(symbolName string) bool {
 	var funcType *btf.Func
@@ -206,6 +306,42 @@ func (b *btfSpecWrapper) TypeByName(name string, typ interface{}) error {
 	return b.spec.TypeByName(name, typ)
 }
 
+func (b *btfSpecWrapper) enumByValueName(valueName string) (*btf.Enum, error) {
+	iter := b.spec.Iterate()
+	for iter.Next() {
+		btfEnum, ok := iter.Type.(*btf.Enum)
+		if !ok {
+			continue
+		}
+
+		for _, enumValue := range btfEnum.Values {
+			if enumValue.Name == valueName {
+				return btfEnum, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("enum with value %s: %w", valueName, btf.ErrNotFound)
+}
+
+func (b *btfSpecWrapper) datasecOfVar(v *btf.Var) (*btf.Datasec, error) {
+	iter := b.spec.Iterate()
+	for iter.Next() {
+		datasec, ok := iter.Type.(*btf.Datasec)
+		if !ok {
+			continue
+		}
+
+		for _, varInfo := range datasec.Vars {
+			if varInfo.Type == v {
+				return datasec, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
 func (b *btfSpecWrapper) typeID(typ btf.Type) (btf.TypeID, error) {
 	return b.spec.TypeID(typ)
 }
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+// kernelQualifiersFixture is a BTF spec stripped from the vmlinux BTF of a 6.18 amd64 kernel down to the types that
+// kernelQualifiersSymbols resolve through. Set TKBTF_UPDATE_KERNEL_FIXTURES to regenerate it from the running kernel.
+var kernelQualifiersFixture = filepath.Join("testdata", "linux-6.18_qualifiers.btf")
+
+// kernelQualifiersSymbols returns symbols with fetch args that resolve through the typedefs and qualifiers of real
+// kernel layouts, namely the typedef of an anonymous struct of atomic_t and the volatile skc_state of sock_common.
+func kernelQualifiersSymbols() []*Symbol {
+	return []*Symbol{
+		NewSymbol("iput").AddProbes(
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("count", "u32").FuncParamWithName("inode", "i_count", "counter"),
+				NewFetchArg("count_auto", FetchArgTypeAuto).FuncParamWithName("inode", "i_count", "counter"),
+				NewFetchArg("ino", FetchArgTypeAuto).FuncParamWithName("inode", "i_ino"),
+			),
+		),
+		NewSymbol("tcp_set_state").AddProbes(
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("skc_state", FetchArgTypeAuto).FuncParamWithName("sk", "__sk_common", "skc_state"),
+			),
+		),
+	}
+}
+
+func TestSpec_KernelQualifiedTypes(t *testing.T) {
+	if os.Getenv("TKBTF_UPDATE_KERNEL_FIXTURES") != "" {
+		kernelSpec, err := NewSpecFromKernel()
+		require.NoError(t, err)
+
+		symbols := kernelQualifiersSymbols()
+		for _, symbol := range symbols {
+			require.NoError(t, kernelSpec.BuildSymbol(symbol))
+		}
+		require.NoError(t, kernelSpec.StripAndSave(kernelQualifiersFixture, symbols...))
+	}
+
+	spec, err := NewSpecFromPath(kernelQualifiersFixture, &SpecOptions{
+		arch: "amd64",
+	})
+	require.NoError(t, err)
+
+	// the fixture keeps the real shapes that the fields resolve through
+	var atomicType *btf.Typedef
+	require.NoError(t, spec.spec.TypeByName("atomic_t", &atomicType))
+	atomicStruct, ok := atomicType.Type.(*btf.Struct)
+	require.True(t, ok)
+	require.Empty(t, atomicStruct.Name)
+
+	var sockCommon *btf.Struct
+	require.NoError(t, spec.spec.TypeByName("sock_common", &sockCommon))
+	require.Len(t, sockCommon.Members, 1)
+	require.IsType(t, &btf.Volatile{}, sockCommon.Members[0].Type)
+
+	symbols := kernelQualifiersSymbols()
+	for _, symbol := range symbols {
+		require.NoError(t, spec.BuildSymbol(symbol))
+	}
+
+	require.Equal(t, "count=+336(%di):u32 count_auto=+336(%di):s32 ino=+64(%di):u64",
+		symbols[0].GetProbes()[0].GetTracingEventProbe())
+	require.Equal(t, "skc_state=+18(%di):u8", symbols[1].GetProbes()[0].GetTracingEventProbe())
+}
@@ -55,6 +55,39 @@ func generateBTFSpec() *Spec {
 	}
 	btfTypesMap["int32"] = typeInt32
 
+	typeChar := &btf.Int{
+		Name:     "char",
+		Size:     1,
+		Encoding: btf.Signed,
+	}
+	btfTypesMap["char"] = typeChar
+
+	typeUint32 := &btf.Int{
+		Name:     "unsigned int",
+		Size:     4,
+		Encoding: 0,
+	}
+	btfTypesMap["unsigned int"] = typeUint32
+
+	// typedef struct { int counter; } atomic_t;
+	atomicStructType := &btf.Struct{
+		Name: "",
+		Size: 4,
+		Members: []btf.Member{
+			{
+				Name:         "counter",
+				Type:         typeUint32,
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	atomicType := &btf.Typedef{
+		Name: "atomic_t",
+		Type: atomicStructType,
+	}
+	btfTypesMap["atomic_t"] = atomicType
+
 	iNode := &btf.Struct{
 		Name: "inode",
 		Size: 648,
@@ -65,12 +98,38 @@ func generateBTFSpec() *Spec {
 				Offset:       0,
 				BitfieldSize: 0,
 			},
+			{
+				Name:         "i_state",
+				Type:         typeUint32,
+				Offset:       35,
+				BitfieldSize: 5,
+			},
 			{
 				Name:         "i_ino",
 				Type:         typeInt32,
 				Offset:       512,
 				BitfieldSize: 0,
 			},
+			{
+				Name:         "i_count",
+				Type:         atomicType,
+				Offset:       1024,
+				BitfieldSize: 0,
+			},
+			{
+				Name: "i_flags",
+				Type: &btf.Volatile{
+					Type: typeUint32,
+				},
+				Offset:       1056,
+				BitfieldSize: 0,
+			},
+			{
+				Name:         "__i_ctime",
+				Type:         typeUint32,
+				Offset:       1088,
+				BitfieldSize: 0,
+			},
 		},
 	}
 	btfTypesMap["inode"] = iNode
@@ -111,6 +170,31 @@ func generateBTFSpec() *Spec {
 				Offset:       384,
 				BitfieldSize: 0,
 			},
+			{
+				Name: "d_fsdata",
+				Type: &btf.Typedef{
+					Name: "inode_ptr_t",
+					Type: &btf.Const{
+						Type: &btf.Pointer{
+							Target: &btf.Volatile{
+								Type: iNode,
+							},
+						},
+					},
+				},
+				Offset:       704,
+				BitfieldSize: 0,
+			},
+			{
+				Name: "d_iname",
+				Type: &btf.Array{
+					Index:  typeInt32,
+					Type:   typeChar,
+					Nelems: 32,
+				},
+				Offset:       448,
+				BitfieldSize: 0,
+			},
 		},
 	}
 	btfTypesMap["dentry"] = dEntry
@@ -140,6 +224,38 @@ func generateBTFSpec() *Spec {
 	}
 	btfTypesMap["an_enum"] = enumType
 
+	kernelGidType := &btf.Typedef{
+		Name: "__kernel_gid32_t",
+		Type: typeUint32,
+	}
+	btfTypesMap["__kernel_gid32_t"] = kernelGidType
+
+	gidType := &btf.Typedef{
+		Name: "gid_t",
+		Type: kernelGidType,
+	}
+	btfTypesMap["gid_t"] = gidType
+
+	kgidStructType := &btf.Struct{
+		Name: "",
+		Size: 4,
+		Members: []btf.Member{
+			{
+				Name:         "val",
+				Type:         gidType,
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["kgid_t_struct"] = kgidStructType
+
+	kgidType := &btf.Typedef{
+		Name: "kgid_t",
+		Type: kgidStructType,
+	}
+	btfTypesMap["kgid_t"] = kgidType
+
 	credStructType := &btf.Struct{
 		Name: "cred",
 		Size: 176,
@@ -150,6 +266,18 @@ func generateBTFSpec() *Spec {
 				Offset:       32,
 				BitfieldSize: 0,
 			},
+			{
+				Name:         "gid",
+				Type:         gidType,
+				Offset:       64,
+				BitfieldSize: 0,
+			},
+			{
+				Name:         "egid",
+				Type:         kgidType,
+				Offset:       96,
+				BitfieldSize: 0,
+			},
 		},
 	}
 	btfTypesMap["cred"] = credStructType
@@ -204,29 +332,111 @@ func generateBTFSpec() *Spec {
 	taskStructType := &btf.Struct{
 		Name: "task_struct",
 		Size: 4160,
+	}
+
+	listHeadType := &btf.Struct{
+		Name: "list_head",
+		Size: 16,
+	}
+	listHeadType.Members = []btf.Member{
+		{
+			Name: "next",
+			Type: &btf.Pointer{
+				Target: listHeadType,
+			},
+			Offset:       0,
+			BitfieldSize: 0,
+		},
+		{
+			Name: "prev",
+			Type: &btf.Pointer{
+				Target: listHeadType,
+			},
+			Offset:       64,
+			BitfieldSize: 0,
+		},
+	}
+	btfTypesMap["list_head"] = listHeadType
+
+	// anonymous union and struct members that wrap task_struct fields, e.g. in kernels with randomized layouts
+	embeddedAnonUnionType := &btf.Union{
+		Name: "",
+		Size: 8,
 		Members: []btf.Member{
 			{
-				Name:         "pid",
-				Type:         typeInt32,
-				Offset:       12032,
+				Name: "real_parent",
+				Type: &btf.Pointer{
+					Target: taskStructType,
+				},
+				Offset:       0,
 				BitfieldSize: 0,
 			},
 			{
-				Name:         "tgid",
-				Type:         typeInt32,
-				Offset:       12064,
-				BitfieldSize: 0,
+				Name:         "exit_state",
+				Type:         typeUint32,
+				Offset:       3,
+				BitfieldSize: 2,
 			},
+		},
+	}
+	btfTypesMap["task_struct_anon_union"] = embeddedAnonUnionType
+
+	embeddedAnonStructType := &btf.Struct{
+		Name: "",
+		Size: 24,
+		Members: []btf.Member{
 			{
-				Name: "",
+				Name: "group_leader",
 				Type: &btf.Pointer{
-					Target: anonStructType,
+					Target: taskStructType,
 				},
-				Offset:       32,
+				Offset:       64,
+				BitfieldSize: 0,
+			},
+			{
+				Name:         "",
+				Type:         embeddedAnonUnionType,
+				Offset:       128,
 				BitfieldSize: 0,
 			},
 		},
 	}
+	btfTypesMap["task_struct_anon_struct"] = embeddedAnonStructType
+
+	taskStructType.Members = []btf.Member{
+		{
+			Name:         "pid",
+			Type:         typeInt32,
+			Offset:       12032,
+			BitfieldSize: 0,
+		},
+		{
+			Name:         "tgid",
+			Type:         typeInt32,
+			Offset:       12064,
+			BitfieldSize: 0,
+		},
+		{
+			Name: "",
+			Type: &btf.Pointer{
+				Target: anonStructType,
+			},
+			Offset:       32,
+			BitfieldSize: 0,
+		},
+		{
+			Name:         "",
+			Type:         embeddedAnonStructType,
+			Offset:       1024,
+			BitfieldSize: 0,
+		},
+		{
+			Name:         "tasks",
+			Type:         listHeadType,
+			Offset:       2048,
+			BitfieldSize: 0,
+		},
+	}
 	btfTypesMap["task_struct"] = taskStructType
 
 	functionTypeProto := &btf.FuncProto{
@@ -289,6 +499,313 @@ func generateBTFSpec() *Spec {
 	}
 	btfTypesMap["test_function_with_ret"] = functionWithRetType
 
+	functionInodesProto := &btf.FuncProto{
+		Return: typeInt32,
+		Params: []btf.FuncParam{
+			{
+				Name: "mask",
+				Type: typeUint32,
+			},
+			{
+				Name: "dir",
+				Type: &btf.Pointer{
+					Target: &btf.Const{
+						Type: iNode,
+					},
+				},
+			},
+			{
+				Name: "inode",
+				Type: &btf.Pointer{
+					Target: iNode,
+				},
+			},
+		},
+	}
+	btfTypesMap["test_function_inodes_proto"] = functionInodesProto
+
+	functionInodesType := &btf.Func{
+		Name:    "test_function_inodes",
+		Type:    functionInodesProto,
+		Linkage: 0,
+	}
+	btfTypesMap["test_function_inodes"] = functionInodesType
+
+	sockAddrStruct := &btf.Struct{
+		Name: "sockaddr",
+		Size: 16,
+		Members: []btf.Member{
+			{
+				Name:         "sa_family",
+				Type:         typeInt16,
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+			{
+				Name: "sa_data",
+				Type: &btf.Array{
+					Index:  typeInt32,
+					Type:   typeChar,
+					Nelems: 14,
+				},
+				Offset:       16,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["sockaddr"] = sockAddrStruct
+
+	userMsgHdrStruct := &btf.Struct{
+		Name: "user_msghdr",
+		Size: 56,
+		Members: []btf.Member{
+			{
+				Name: "msg_name",
+				Type: &btf.Pointer{
+					Target: &btf.TypeTag{
+						Type:  sockAddrStruct,
+						Value: "user",
+					},
+				},
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+			{
+				Name:         "msg_namelen",
+				Type:         typeUint32,
+				Offset:       64,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["user_msghdr"] = userMsgHdrStruct
+
+	functionUserProto := &btf.FuncProto{
+		Return: typeInt32,
+		Params: []btf.FuncParam{
+			{
+				Name: "filename",
+				Type: &btf.Pointer{
+					Target: &btf.TypeTag{
+						Type: &btf.Const{
+							Type: typeChar,
+						},
+						Value: "user",
+					},
+				},
+			},
+			{
+				Name: "msg",
+				Type: &btf.Pointer{
+					Target: &btf.TypeTag{
+						Type:  userMsgHdrStruct,
+						Value: "user",
+					},
+				},
+			},
+		},
+	}
+	btfTypesMap["test_function_user_proto"] = functionUserProto
+
+	functionUserType := &btf.Func{
+		Name:    "test_function_user",
+		Type:    functionUserProto,
+		Linkage: 0,
+	}
+	btfTypesMap["test_function_user"] = functionUserType
+
+	functionListProto := &btf.FuncProto{
+		Return: typeInt32,
+		Params: []btf.FuncParam{
+			{
+				Name: "node",
+				Type: &btf.Pointer{
+					Target: listHeadType,
+				},
+			},
+		},
+	}
+	btfTypesMap["test_function_list_proto"] = functionListProto
+
+	functionListType := &btf.Func{
+		Name:    "test_function_list",
+		Type:    functionListProto,
+		Linkage: 0,
+	}
+	btfTypesMap["test_function_list"] = functionListType
+
+	typeUint64 := &btf.Int{
+		Name:     "long unsigned int",
+		Size:     8,
+		Encoding: 0,
+	}
+	btfTypesMap["long unsigned int"] = typeUint64
+
+	pidNamespaceType := &btf.Struct{
+		Name: "pid_namespace",
+		Size: 136,
+		Members: []btf.Member{
+			{
+				Name:         "level",
+				Type:         typeUint32,
+				Offset:       64,
+				BitfieldSize: 0,
+			},
+			{
+				Name: "child_reaper",
+				Type: &btf.Pointer{
+					Target: taskStructType,
+				},
+				Offset:       128,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["pid_namespace"] = pidNamespaceType
+
+	jiffiesVar := &btf.Var{
+		Name:    "jiffies",
+		Type:    &btf.Volatile{Type: typeUint64},
+		Linkage: btf.GlobalVar,
+	}
+	btfTypesMap["jiffies"] = jiffiesVar
+
+	initPidNsVar := &btf.Var{
+		Name:    "init_pid_ns",
+		Type:    pidNamespaceType,
+		Linkage: btf.GlobalVar,
+	}
+	btfTypesMap["init_pid_ns"] = initPidNsVar
+
+	savedCommandLineVar := &btf.Var{
+		Name: "saved_command_line",
+		Type: &btf.Pointer{
+			Target: typeChar,
+		},
+		Linkage: btf.GlobalVar,
+	}
+	btfTypesMap["saved_command_line"] = savedCommandLineVar
+
+	linuxBannerVar := &btf.Var{
+		Name: "linux_banner",
+		Type: &btf.Array{
+			Index:  typeInt32,
+			Type:   &btf.Const{Type: typeChar},
+			Nelems: 64,
+		},
+		Linkage: btf.GlobalVar,
+	}
+	btfTypesMap["linux_banner"] = linuxBannerVar
+
+	runqueuesVar := &btf.Var{
+		Name:    "runqueues",
+		Type:    pidNamespaceType,
+		Linkage: btf.GlobalVar,
+	}
+	btfTypesMap["runqueues"] = runqueuesVar
+
+	btfTypesMap[".data"] = &btf.Datasec{
+		Name: ".data",
+		Size: 1024,
+		Vars: []btf.VarSecinfo{
+			{Type: jiffiesVar, Offset: 0, Size: 8},
+			{Type: initPidNsVar, Offset: 8, Size: 136},
+			{Type: savedCommandLineVar, Offset: 144, Size: 8},
+			{Type: linuxBannerVar, Offset: 152, Size: 64},
+		},
+	}
+
+	btfTypesMap[".data..percpu"] = &btf.Datasec{
+		Name: ".data..percpu",
+		Size: 136,
+		Vars: []btf.VarSecinfo{
+			{Type: runqueuesVar, Offset: 0, Size: 136},
+		},
+	}
+
+	readIterProto := &btf.FuncProto{
+		Return: typeInt32,
+		Params: []btf.FuncParam{
+			{
+				Name: "iocb",
+				Type: &btf.Pointer{
+					Target: &btf.Void{},
+				},
+			},
+		},
+	}
+	btfTypesMap["read_iter_proto"] = readIterProto
+
+	llseekType := &btf.Typedef{
+		Name: "llseek_fn_t",
+		Type: &btf.Pointer{
+			Target: readIterProto,
+		},
+	}
+	btfTypesMap["llseek_fn_t"] = llseekType
+
+	fileOperationsStruct := &btf.Struct{
+		Name: "file_operations",
+		Size: 16,
+		Members: []btf.Member{
+			{
+				Name:         "llseek",
+				Type:         llseekType,
+				Offset:       0,
+				BitfieldSize: 0,
+			},
+			{
+				Name: "read_iter",
+				Type: &btf.Pointer{
+					Target: readIterProto,
+				},
+				Offset:       64,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["file_operations"] = fileOperationsStruct
+
+	fileStruct := &btf.Struct{
+		Name: "file",
+		Size: 48,
+		Members: []btf.Member{
+			{
+				Name: "f_op",
+				Type: &btf.Pointer{
+					Target: &btf.Const{
+						Type: fileOperationsStruct,
+					},
+				},
+				Offset:       320,
+				BitfieldSize: 0,
+			},
+		},
+	}
+	btfTypesMap["file"] = fileStruct
+
+	functionFileProto := &btf.FuncProto{
+		Return: &btf.Pointer{
+			Target: readIterProto,
+		},
+		Params: []btf.FuncParam{
+			{
+				Name: "file",
+				Type: &btf.Pointer{
+					Target: fileStruct,
+				},
+			},
+		},
+	}
+	btfTypesMap["test_function_file_proto"] = functionFileProto
+
+	functionFileType := &btf.Func{
+		Name:    "test_function_file",
+		Type:    functionFileProto,
+		Linkage: 0,
+	}
+	btfTypesMap["test_function_file"] = functionFileType
+
 	return &Spec{
 		spec: newMockedBTFSpecWithTypesMap(btfTypesMap),
 		regs: &registersAmd64{},
@@ -311,6 +828,16 @@ func (m *mockedBTFSpec) AnyTypesByName(name string) ([]btf.Type, error) {
 	return args.Get(0).([]btf.Type), args.Error(1)
 }
 
+func (m *mockedBTFSpec) enumByValueName(valueName string) (*btf.Enum, error) {
+	args := m.Called(valueName)
+	return args.Get(0).(*btf.Enum), args.Error(1)
+}
+
+func (m *mockedBTFSpec) datasecOfVar(v *btf.Var) (*btf.Datasec, error) {
+	args := m.Called(v)
+	return args.Get(0).(*btf.Datasec), args.Error(1)
+}
+
 func (m *mockedBTFSpec) copy() btfSpec {
 	args := m.Called()
 	return args.Get(0).(btfSpec)
@@ -332,15 +859,65 @@ func newMockedBTFSpecWithTypesMap(mapTypes map[string]btf.Type) btfSpec {
 		Ids:   make(map[btf.Type]btf.TypeID),
 	}
 
-	counter := 0
 	for _, typ := range mapTypes {
-		mockedSpec.Ids[typ] = btf.TypeID(counter)
-		counter++
+		addMockedTypeIDs(mockedSpec.Ids, typ)
 	}
 
 	return mockedSpec
 }
 
+// addMockedTypeIDs assigns an id to the given type and to every type reachable from it, so that nested types,
+// e.g. the anonymous struct of typedef struct { int counter; } atomic_t, get one without being mapped by name.
+func addMockedTypeIDs(ids map[btf.Type]btf.TypeID, typ btf.Type) {
+	if typ == nil {
+		return
+	}
+
+	if _, exists := ids[typ]; exists {
+		return
+	}
+	ids[typ] = btf.TypeID(len(ids))
+
+	switch t := typ.(type) {
+	case *btf.Pointer:
+		addMockedTypeIDs(ids, t.Target)
+	case *btf.Const:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.Volatile:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.Restrict:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.TypeTag:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.Typedef:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.Array:
+		addMockedTypeIDs(ids, t.Type)
+		addMockedTypeIDs(ids, t.Index)
+	case *btf.Struct:
+		for _, m := range t.Members {
+			addMockedTypeIDs(ids, m.Type)
+		}
+	case *btf.Union:
+		for _, m := range t.Members {
+			addMockedTypeIDs(ids, m.Type)
+		}
+	case *btf.Func:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.FuncProto:
+		addMockedTypeIDs(ids, t.Return)
+		for _, p := range t.Params {
+			addMockedTypeIDs(ids, p.Type)
+		}
+	case *btf.Var:
+		addMockedTypeIDs(ids, t.Type)
+	case *btf.Datasec:
+		for _, v := range t.Vars {
+			addMockedTypeIDs(ids, v.Type)
+		}
+	}
+}
+
 func (m *mockedBTFSpecWithTypesMap) typeID(t btf.Type) (btf.TypeID, error) {
 	typId, exists := m.Ids[t]
 	if exists {
@@ -405,6 +982,40 @@ func (m *mockedBTFSpecWithTypesMap) copy() btfSpec {
 	}
 }
 
+func (m *mockedBTFSpecWithTypesMap) enumByValueName(valueName string) (*btf.Enum, error) {
+	for _, t := range m.Types {
+		btfEnum, ok := t.(*btf.Enum)
+		if !ok {
+			continue
+		}
+
+		for _, enumValue := range btfEnum.Values {
+			if enumValue.Name == valueName {
+				return btfEnum, nil
+			}
+		}
+	}
+
+	return nil, errors.New("not found")
+}
+
+func (m *mockedBTFSpecWithTypesMap) datasecOfVar(v *btf.Var) (*btf.Datasec, error) {
+	for _, t := range m.Types {
+		datasec, ok := t.(*btf.Datasec)
+		if !ok {
+			continue
+		}
+
+		for _, varInfo := range datasec.Vars {
+			if varInfo.Type == v {
+				return datasec, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
 func (m *mockedBTFSpecWithTypesMap) AnyTypesByName(name string) ([]btf.Type, error) {
 	t, exists := m.Types[name]
 	if !exists {
@@ -132,6 +132,160 @@ func TestSpec(t *testing.T) {
 	}
 }
 
+func TestSpec_StripAndRebuild(t *testing.T) {
+	tcs := []struct {
+		name   string
+		symbol *Symbol
+		// configure is applied to both the spec that the symbol is built against and the stripped one
+		configure func(spec *Spec)
+		// unconfiguredErr, if set, is the error of building the symbol against the stripped spec before configuring it
+		unconfiguredErr error
+		expected        string
+		kept            []string
+		stripped        []string
+		check           func(t *testing.T, strippedSpec *Spec)
+	}{
+		{
+			name: "user_space_types",
+			symbol: NewSymbol("test_function_user").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("filename"),
+					NewFetchArg("fa2", "u16").FuncParamWithName("msg", "msg_name", "sa_family"),
+				),
+			),
+			expected: "fa1=+0(%x0):ustring fa2=+u0(+u0(%x1)):u16",
+			kept:     []string{"user_msghdr", "sockaddr"},
+		},
+		{
+			name: "qualified_types",
+			symbol: NewSymbol("test_function").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_count", "counter"),
+					NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("inode_param", "i_flags"),
+					NewFetchArg("fa3", "u64").FuncParamWithName("dentry_param", "d_fsdata", "i_ino"),
+				),
+			),
+			expected: "fa1=+128(%x1):u32 fa2=+132(%x1):u32 fa3=+64(+88(%x0)):u64",
+			kept:     []string{"atomic_t"},
+			stripped: []string{"qstr"},
+		},
+		{
+			name: "anonymous_members",
+			symbol: NewSymbol("test_function_with_ret").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", "u32").FuncParamWithName("tsk_param", "group_leader", "real_parent", "tgid"),
+					NewFetchArg("fa2", FetchArgTypeAuto).FuncParamWithName("tsk_param", "exit_state"),
+				),
+			),
+			configure: func(spec *Spec) {
+				spec.SetAnonymousMemberLookup(true)
+			},
+			unconfiguredErr: ErrFieldNotFound,
+			expected:        "fa1=+1508(+144(+136(%x2))):u32 fa2=+144(%x2):b2@3/32",
+		},
+		{
+			name: "container_of",
+			symbol: NewSymbol("test_function_list").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", "u32").FromExpression("container_of(node->next, struct task_struct, tasks)->pid"),
+				),
+			),
+			expected: "fa1=+1248(+0(%x0)):u32",
+			kept:     []string{"task_struct"},
+			stripped: []string{"dentry"},
+		},
+		{
+			name: "global_variable",
+			symbol: NewSymbol("test_function").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", "u64").GlobalVariable("jiffies"),
+					NewFetchArg("fa2", "u32").GlobalVariable("init_pid_ns", "child_reaper", "pid"),
+				),
+			),
+			expected: "fa1=@jiffies:u64 fa2=+1504(@init_pid_ns+16):u32",
+			kept:     []string{"init_pid_ns", ".data"},
+			stripped: []string{"linux_banner"},
+		},
+		{
+			name: "func_pointer",
+			symbol: NewSymbol("test_function_file").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", FetchArgTypeAuto).FuncParamWithName("file", "f_op", "read_iter"),
+				),
+			),
+			expected: "fa1=+8(+40(%x0)):symbol",
+			kept:     []string{"file_operations"},
+			stripped: []string{"llseek_fn_t"},
+		},
+		{
+			name: "field_alias",
+			symbol: NewSymbol("test_function").AddProbes(
+				NewKProbe().AddFetchArgs(
+					NewFetchArg("fa1", "u32").FuncParamWithName("inode_param", "i_ctime"),
+				),
+			),
+			configure: func(spec *Spec) {
+				spec.AddFieldAlias("inode", "i_ctime", "__i_ctime")
+			},
+			unconfiguredErr: ErrFieldNotFound,
+			expected:        "fa1=+136(%x1):u32",
+			check: func(t *testing.T, strippedSpec *Spec) {
+				// only the resolved member is kept
+				var inodeType *btf.Struct
+				require.NoError(t, strippedSpec.spec.TypeByName("inode", &inodeType))
+				require.Len(t, inodeType.Members, 1)
+				require.Equal(t, "__i_ctime", inodeType.Members[0].Name)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := generateBTFSpec()
+			var err error
+			spec.regs, err = getRegistersResolver("arm64")
+			require.NoError(t, err)
+			if tc.configure != nil {
+				tc.configure(spec)
+			}
+
+			require.NoError(t, spec.BuildSymbol(tc.symbol))
+			require.Equal(t, tc.expected, tc.symbol.GetProbes()[0].GetTracingEventProbe())
+
+			fileName := filepath.Join(t.TempDir(), "btfFile")
+			require.NoError(t, spec.StripAndSave(fileName, tc.symbol))
+
+			// load stripped spec from path; NOTE this an actual implementation of *btf.Spec
+			pathSpec, err := NewSpecFromPath(fileName, &SpecOptions{
+				arch: "arm64",
+			})
+			require.NoError(t, err)
+
+			for _, typeName := range tc.kept {
+				_, err = pathSpec.spec.AnyTypesByName(typeName)
+				require.NoError(t, err, typeName)
+			}
+			for _, typeName := range tc.stripped {
+				_, err = pathSpec.spec.AnyTypesByName(typeName)
+				require.ErrorIs(t, err, btf.ErrNotFound, typeName)
+			}
+			if tc.check != nil {
+				tc.check(t, pathSpec)
+			}
+
+			// build symbol with the new spec
+			if tc.unconfiguredErr != nil {
+				require.ErrorIs(t, pathSpec.BuildSymbol(tc.symbol), tc.unconfiguredErr)
+			}
+			if tc.configure != nil {
+				tc.configure(pathSpec)
+			}
+			require.NoError(t, pathSpec.BuildSymbol(tc.symbol))
+			require.Equal(t, tc.expected, tc.symbol.GetProbes()[0].GetTracingEventProbe())
+		})
+	}
+}
+
 func TestSpec_ContainsSymbol(t *testing.T) {
 	mockSpec := &Spec{
 		spec: newMockedBTFSpecWithTypesMap(map[string]btf.Type{
@@ -143,3 +297,40 @@ func TestSpec_ContainsSymbol(t *testing.T) {
 	require.False(t, mockSpec.ContainsSymbol("unknown"))
 	require.True(t, mockSpec.ContainsSymbol("dentry"))
 }
+
+func TestSpec_BuildSymbols(t *testing.T) {
+	spec := generateBTFSpec()
+
+	builtSymbol := NewSymbol("test_function").AddProbes(
+		NewKProbe().AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_ino"),
+		),
+	)
+	optionalSymbol := NewSymbol("fsnotify_nameremove").SetOptional(true).AddProbes(
+		NewKProbe().AddFetchArgs(
+			NewFetchArg("mid", "u32").FuncParamWithName("isdir"),
+		),
+	)
+	requiredSymbol := NewSymbol("test_function").AddProbes(
+		NewKProbe().AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_unknown"),
+		),
+	)
+
+	t.Run("required_symbols_built", func(t *testing.T) {
+		result, err := spec.BuildSymbols(builtSymbol, optionalSymbol)
+		require.NoError(t, err)
+		require.Equal(t, []*Symbol{builtSymbol}, result.GetBuiltSymbols())
+		require.Equal(t, []*Symbol{optionalSymbol}, result.GetSkippedSymbols())
+		require.Len(t, result.GetSkippedErrors(), 1)
+		require.ErrorIs(t, result.GetSkippedErrors()[0], ErrSymbolNotFound)
+	})
+
+	t.Run("required_symbol_failed", func(t *testing.T) {
+		result, err := spec.BuildSymbols(requiredSymbol, optionalSymbol, builtSymbol)
+		require.ErrorIs(t, err, ErrFieldNotFound)
+		require.NotErrorIs(t, err, ErrSymbolNotFound)
+		require.Equal(t, []*Symbol{builtSymbol}, result.GetBuiltSymbols())
+		require.Equal(t, []*Symbol{optionalSymbol}, result.GetSkippedSymbols())
+	})
+}
@@ -18,7 +18,6 @@
 package tkbtf
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/cilium/ebpf/btf"
@@ -27,13 +26,52 @@ import (
 type typeToStrip struct {
 	typ          btf.Type
 	fieldsToKeep map[string]struct{}
+	// anonymousToKeep holds the types of the anonymous members to keep, since these can't be told apart by name
+	anonymousToKeep map[btf.Type]struct{}
 }
 
 type typesToStripMap map[btf.TypeID]*typeToStrip
 
 // addType adds a type to the typesToStripMap.
 func (t typesToStripMap) addType(spec btfSpec, typ btf.Type) error {
-	return t.addTypeField(spec, typ, "")
+	_, err := t.typeEntry(spec, typ)
+	return err
+}
+
+// typeEntry returns the entry of the typesToStripMap for the given type, after skipping any pointers, qualifiers,
+// typedefs and arrays. If the entry does not exist, it is created.
+func (t typesToStripMap) typeEntry(spec btfSpec, typ btf.Type) (*typeToStrip, error) {
+	switch tt := typ.(type) {
+	case *btf.Pointer:
+		return t.typeEntry(spec, tt.Target)
+	case *btf.Const:
+		return t.typeEntry(spec, tt.Type)
+	case *btf.Volatile:
+		return t.typeEntry(spec, tt.Type)
+	case *btf.Restrict:
+		return t.typeEntry(spec, tt.Type)
+	case *btf.TypeTag:
+		return t.typeEntry(spec, tt.Type)
+	case *btf.Typedef:
+		return t.typeEntry(spec, tt.Type)
+	case *btf.Array:
+		return t.typeEntry(spec, tt.Type)
+	}
+
+	id, err := spec.typeID(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, exists := t[id]; !exists {
+		t[id] = &typeToStrip{
+			typ:             typ,
+			fieldsToKeep:    make(map[string]struct{}),
+			anonymousToKeep: make(map[btf.Type]struct{}),
+		}
+	}
+
+	return t[id], nil
 }
 
 // addType adds a type to the typesToStripMap and a field to keep of that type.
@@ -43,6 +81,12 @@ func (t typesToStripMap) addTypeField(spec btfSpec, typ btf.Type, field string)
 		return t.addTypeField(spec, tt.Target, field)
 	case *btf.Const:
 		return t.addTypeField(spec, tt.Type, field)
+	case *btf.Volatile:
+		return t.addTypeField(spec, tt.Type, field)
+	case *btf.Restrict:
+		return t.addTypeField(spec, tt.Type, field)
+	case *btf.TypeTag:
+		return t.addTypeField(spec, tt.Type, field)
 	case *btf.Typedef:
 		return t.addTypeField(spec, tt.Type, field)
 	case *btf.Array:
@@ -50,14 +94,8 @@ func (t typesToStripMap) addTypeField(spec btfSpec, typ btf.Type, field string)
 	}
 
 	if _, ok := typ.(*btf.Enum); !ok && strings.HasPrefix(field, "enum:") {
-		enumTokens := strings.Split(field, ":")
-		if len(enumTokens) != 3 {
-			return fmt.Errorf("index from enum invalid format: %w", ErrArrayIndexInvalidField)
-		}
-
-		enumName := enumTokens[1]
-		var btfEnum *btf.Enum
-		if err := spec.TypeByName(enumName, &btfEnum); err != nil {
+		btfEnum, _, err := indexEnum(spec, field)
+		if err != nil {
 			return err
 		}
 		if err := t.addTypeField(spec, btfEnum, field); err != nil {
@@ -65,22 +103,40 @@ func (t typesToStripMap) addTypeField(spec btfSpec, typ btf.Type, field string)
 		}
 	}
 
-	id, err := spec.typeID(typ)
+	entry, err := t.typeEntry(spec, typ)
 	if err != nil {
 		return err
 	}
 
-	if _, exists := t[id]; !exists {
-		t[id] = &typeToStrip{
-			typ:          typ,
-			fieldsToKeep: make(map[string]struct{}),
-		}
+	entry.fieldsToKeep[field] = struct{}{}
+	return nil
+}
+
+// addTypeAnonymousMember adds a type to the typesToStripMap and the anonymous member of the given type to keep.
+func (t typesToStripMap) addTypeAnonymousMember(spec btfSpec, typ btf.Type, memberType btf.Type) error {
+	entry, err := t.typeEntry(spec, typ)
+	if err != nil {
+		return err
 	}
 
-	t[id].fieldsToKeep[field] = struct{}{}
+	entry.anonymousToKeep[memberType] = struct{}{}
 	return nil
 }
 
+// keepMember returns true if the given member of the type should be kept.
+func (t *typeToStrip) keepMember(member btf.Member) bool {
+	if _, exists := t.fieldsToKeep[member.Name]; exists {
+		return true
+	}
+
+	if member.Name != "" {
+		return false
+	}
+
+	_, exists := t.anonymousToKeep[member.Type]
+	return exists
+}
+
 // checkTypeInMap checks if a type is in the typesToStripMap.
 func (t typesToStripMap) checkTypeInMap(spec btfSpec, typ btf.Type) bool {
 	switch tt := typ.(type) {
@@ -88,6 +144,12 @@ func (t typesToStripMap) checkTypeInMap(spec btfSpec, typ btf.Type) bool {
 		return t.checkTypeInMap(spec, tt.Target)
 	case *btf.Const:
 		return t.checkTypeInMap(spec, tt.Type)
+	case *btf.Volatile:
+		return t.checkTypeInMap(spec, tt.Type)
+	case *btf.Restrict:
+		return t.checkTypeInMap(spec, tt.Type)
+	case *btf.TypeTag:
+		return t.checkTypeInMap(spec, tt.Type)
 	case *btf.Typedef:
 		return t.checkTypeInMap(spec, tt.Type)
 	case *btf.Array:
@@ -112,7 +174,7 @@ func (t typesToStripMap) strip(spec btfSpec) {
 			var newMembers []btf.Member
 
 			for _, member := range allMembers {
-				if _, exists := typ.fieldsToKeep[member.Name]; exists {
+				if typ.keepMember(member) {
 					newMembers = append(newMembers, member)
 				}
 			}
@@ -123,12 +185,23 @@ func (t typesToStripMap) strip(spec btfSpec) {
 			var newMembers []btf.Member
 
 			for _, member := range allMembers {
-				if _, exists := typ.fieldsToKeep[member.Name]; exists {
+				if typ.keepMember(member) {
 					newMembers = append(newMembers, member)
 				}
 			}
 
 			tt.Members = newMembers
+		case *btf.Datasec:
+			allVars := tt.Vars
+			var newVars []btf.VarSecinfo
+
+			for _, v := range allVars {
+				if t.checkTypeInMap(spec, v.Type) {
+					newVars = append(newVars, v)
+				}
+			}
+
+			tt.Vars = newVars
 		case *btf.FuncProto:
 			allParams := tt.Params
 			var newParams []btf.FuncParam
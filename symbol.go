@@ -27,10 +27,14 @@ import (
 
 // Symbol represents a function symbol and holds the list of probes associated with it.
 type Symbol struct {
-	names           []string
-	probes          []*Probe
-	foundSymbolName string
-	skipValidation  bool
+	names  []string
+	probes []*Probe
+	// alternatives holds the alternatives of every probe in probes, a probe added with AddProbes has only itself
+	alternatives       [][]*Probe
+	chosenAlternatives []int
+	foundSymbolName    string
+	skipValidation     bool
+	optional           bool
 }
 
 // NewSymbol creates and returns a new Symbol instance with the given symbol names.
@@ -66,28 +70,59 @@ func NewSymbolWithoutValidation(symbolName string) *Symbol {
 	return symbol
 }
 
+// SetOptional marks the Symbol as optional. When an optional Symbol fails to build in Spec.BuildSymbols, it is
+// reported as skipped instead of failing the build, e.g. when the symbol exists only in some kernel versions.
+func (s *Symbol) SetOptional(optional bool) *Symbol {
+	s.optional = optional
+	return s
+}
+
 // AddProbes attaches the given probes to the Symbol.
 func (s *Symbol) AddProbes(p ...*Probe) *Symbol {
-	s.probes = append(s.probes, p...)
+	for _, probe := range p {
+		s.probes = append(s.probes, probe)
+		s.alternatives = append(s.alternatives, []*Probe{probe})
+		s.chosenAlternatives = append(s.chosenAlternatives, 0)
+	}
 	return s
 }
 
-// build is a method of the Symbol struct that builds the symbol using the provided btfSpec.
-// It returns an error if any symbol is not found or if there is an error in building the symbol.
-func (s *Symbol) build(spec btfSpec, regs registersResolver) error {
-	var funcType *btf.Func
+// AddProbeAlternatives attaches to the Symbol a probe with the given ordered alternatives. This is useful when
+// different kernel versions need a completely different probe shape, e.g. different function parameters, and not
+// just different field paths. During build, the first alternative that fully builds is the one that GetProbes
+// returns in the position of the probe, and GetChosenAlternatives reports its index. If none of the alternatives
+// builds, all their errors are returned. Before build, the first alternative is returned by GetProbes.
+func (s *Symbol) AddProbeAlternatives(alternatives ...*Probe) *Symbol {
+	if len(alternatives) == 0 {
+		return s
+	}
 
+	s.probes = append(s.probes, alternatives[0])
+	s.alternatives = append(s.alternatives, alternatives)
+	s.chosenAlternatives = append(s.chosenAlternatives, 0)
+	return s
+}
+
+// build is a method of the Symbol struct that builds the symbol using the provided btfSpec. The first symbol name
+// that is found in the btf spec is the resolved one. It returns an error if no symbol is found or if there is an
+// error in building the probes, in which case the state of any previous build is reset.
+func (s *Symbol) build(spec btfSpec, opts resolveOptions, regs registersResolver) error {
 	if len(s.names) == 0 {
 		return ErrMissingSymbolNames
 	}
 
+	s.resetBuild()
+
+	var funcType *btf.Func
+	symbolName := s.names[0]
+
 	// If skipValidation is false, validate each symbol until the first successfully validated
 	if !s.skipValidation {
 		var allErr error
-		for _, symbolName := range s.names {
-			err := spec.TypeByName(symbolName, &funcType)
+		for _, name := range s.names {
+			err := spec.TypeByName(name, &funcType)
 			if err != nil {
-				allErr = errors.Join(allErr, fmt.Errorf("getting func of %s failed: %w", symbolName, ErrSymbolNotFound))
+				allErr = errors.Join(allErr, fmt.Errorf("getting func of %s failed: %w", name, ErrSymbolNotFound))
 				continue
 			}
 			break
@@ -97,14 +132,47 @@ func (s *Symbol) build(spec btfSpec, regs registersResolver) error {
 			return allErr
 		}
 
-		s.foundSymbolName = funcType.Name
-	} else {
-		s.foundSymbolName = s.names[0]
+		symbolName = funcType.Name
+	}
+
+	if err := s.buildProbes(symbolName, spec, opts, funcType, regs); err != nil {
+		s.resetBuild()
+		return err
+	}
+
+	s.foundSymbolName = symbolName
+	return nil
+}
+
+// resetBuild resets the resolved symbol name and the chosen alternatives of the probes to the state before build.
+func (s *Symbol) resetBuild() {
+	s.foundSymbolName = ""
+	for i, alternatives := range s.alternatives {
+		s.probes[i] = alternatives[0]
+		s.chosenAlternatives[i] = 0
 	}
+}
 
-	for _, p := range s.probes {
-		if err := p.build(s.foundSymbolName, spec, funcType, regs); err != nil {
-			return err
+// buildProbes builds the probes of the Symbol for the given symbol name. For every probe, its alternatives are tried
+// in order until the first that builds successfully.
+func (s *Symbol) buildProbes(symbolName string, spec btfSpec, opts resolveOptions, funcType *btf.Func, regs registersResolver) error {
+	for i, alternatives := range s.alternatives {
+		var allErr error
+		built := false
+		for j, p := range alternatives {
+			if err := p.build(symbolName, spec, opts, funcType, regs); err != nil {
+				allErr = errors.Join(allErr, err)
+				continue
+			}
+
+			s.probes[i] = p
+			s.chosenAlternatives[i] = j
+			built = true
+			break
+		}
+
+		if !built {
+			return allErr
 		}
 	}
 
@@ -119,7 +187,14 @@ func (s *Symbol) GetSymbolName() string {
 	return s.foundSymbolName
 }
 
-// GetProbes returns the list of probes attached to the Symbol.
+// GetProbes returns the list of probes attached to the Symbol. For probes with alternatives, the alternative that
+// was chosen during build is returned.
 func (s *Symbol) GetProbes() []*Probe {
 	return s.probes
 }
+
+// GetChosenAlternatives returns, for every probe returned by GetProbes, the index of the alternative that was chosen
+// during build, see AddProbeAlternatives. Probes without alternatives always report zero.
+func (s *Symbol) GetChosenAlternatives() []int {
+	return s.chosenAlternatives
+}
@@ -0,0 +1,41 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+// SymbolsBuildResult holds the outcome of Spec.BuildSymbols.
+type SymbolsBuildResult struct {
+	built       []*Symbol
+	skipped     []*Symbol
+	skippedErrs []error
+}
+
+// GetBuiltSymbols returns the symbols that were built successfully, respecting the order they were given.
+func (r *SymbolsBuildResult) GetBuiltSymbols() []*Symbol {
+	return r.built
+}
+
+// GetSkippedSymbols returns the optional symbols that failed to build and thus were skipped, respecting the order
+// they were given.
+func (r *SymbolsBuildResult) GetSkippedSymbols() []*Symbol {
+	return r.skipped
+}
+
+// GetSkippedErrors returns the build errors of the skipped symbols. The errors follow the order of GetSkippedSymbols.
+func (r *SymbolsBuildResult) GetSkippedErrors() []error {
+	return r.skippedErrs
+}
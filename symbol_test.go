@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbol_ProbeAlternatives(t *testing.T) {
+	spec := generateBTFSpec()
+
+	t.Run("first_alternative_that_builds", func(t *testing.T) {
+		isDirProbe := NewKProbe().SetRef("nameremove").AddFetchArgs(
+			NewFetchArg("mid", "u32").FuncParamWithName("isdir"),
+		)
+		maskProbe := NewKProbe().SetRef("parent").AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_ino"),
+		)
+		plainProbe := NewKRetProbe().AddFetchArgs(
+			NewFetchArg("ret", "u16").FuncReturn(),
+		)
+
+		symbol := NewSymbol("test_function").AddProbes(plainProbe).AddProbeAlternatives(isDirProbe, maskProbe)
+		require.Equal(t, []*Probe{plainProbe, isDirProbe}, symbol.GetProbes())
+
+		err := spec.BuildSymbol(symbol)
+		require.NoError(t, err)
+		require.Equal(t, "test_function", symbol.GetSymbolName())
+		require.Equal(t, []*Probe{plainProbe, maskProbe}, symbol.GetProbes())
+		require.Equal(t, []int{0, 1}, symbol.GetChosenAlternatives())
+		require.Equal(t, "kprobe_parent", symbol.GetProbes()[1].GetID())
+		require.Equal(t, "fi=+64(%si):u32", symbol.GetProbes()[1].GetTracingEventProbe())
+	})
+
+	t.Run("first_found_symbol_decides", func(t *testing.T) {
+		dentryProbe := NewKProbe().AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+		)
+
+		symbol := NewSymbol("missing_function", "test_function_inodes", "test_function").AddProbeAlternatives(dentryProbe)
+
+		err := spec.BuildSymbol(symbol)
+		require.ErrorIs(t, err, ErrFuncParamNotFound)
+		require.Empty(t, symbol.GetSymbolName())
+	})
+
+	t.Run("state_reset_on_failure", func(t *testing.T) {
+		isDirProbe := NewKProbe().AddFetchArgs(
+			NewFetchArg("mid", "u32").FuncParamWithName("isdir"),
+		)
+		maskProbe := NewKProbe().AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_ino"),
+		)
+		failingProbe := NewKProbe().AddFetchArgs(
+			NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_ctime"),
+		)
+
+		symbol := NewSymbol("test_function").AddProbeAlternatives(isDirProbe, maskProbe)
+		require.NoError(t, spec.BuildSymbol(symbol))
+		require.Equal(t, "test_function", symbol.GetSymbolName())
+		require.Equal(t, []int{1}, symbol.GetChosenAlternatives())
+
+		symbol.AddProbeAlternatives(failingProbe)
+		err := spec.BuildSymbol(symbol)
+		require.ErrorIs(t, err, ErrFieldNotFound)
+		require.Empty(t, symbol.GetSymbolName())
+		require.Equal(t, []*Probe{isDirProbe, failingProbe}, symbol.GetProbes())
+		require.Equal(t, []int{0, 0}, symbol.GetChosenAlternatives())
+	})
+
+	t.Run("no_alternative_builds", func(t *testing.T) {
+		symbol := NewSymbol("test_function").AddProbeAlternatives(
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("mid", "u32").FuncParamWithName("isdir"),
+			),
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("fi", "u32").FuncParamWithName("inode_param", "i_ctime"),
+			),
+		)
+
+		err := spec.BuildSymbol(symbol)
+		require.ErrorIs(t, err, ErrFuncParamNotFound)
+		require.ErrorIs(t, err, ErrFieldNotFound)
+	})
+
+	t.Run("without_validation", func(t *testing.T) {
+		commProbe := NewKProbe().AddFetchArgs(
+			NewFetchArg("comm", "string").Comm(),
+		)
+
+		symbol := NewSymbolWithoutValidation("missing_function").AddProbeAlternatives(
+			NewKProbe().AddFetchArgs(
+				NewFetchArg("ret", "u64").FuncReturnValue(),
+			),
+			commProbe,
+		)
+
+		err := spec.BuildSymbol(symbol)
+		require.NoError(t, err)
+		require.Equal(t, []*Probe{commProbe}, symbol.GetProbes())
+		require.Equal(t, []int{1}, symbol.GetChosenAlternatives())
+		require.Equal(t, "comm=$comm:string", commProbe.GetTracingEventProbe())
+	})
+}
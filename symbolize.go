@@ -0,0 +1,343 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// SymbolizedFetchArg is the field path that a kprobe fetch arg resolves to, see Spec.SymbolizeFetchArg.
+type SymbolizedFetchArg struct {
+	// Name is the name of the fetch arg.
+	Name string
+	// Param is where the field path starts, namely the name of the function parameter, $retval, the global variable,
+	// e.g. @jiffies, or, for fetch args that are not symbolized, their root, e.g. $comm.
+	Param string
+	// ParamIndex is the index of the function parameter, or -1 when the fetch arg doesn't start from one.
+	ParamIndex int
+	// Fields holds the fields in the format that the fieldsBuilders expect, e.g. FuncParamWithName(Param, Fields...).
+	// Anonymous members are omitted, thus resolving the fields requires Spec.SetAnonymousMemberLookup.
+	Fields []string
+	// Path is the C representation of the field path, e.g. path->dentry->d_inode->i_ino.
+	Path string
+	// TypeName is the C declaration of the btf type that the field path resolves to.
+	TypeName string
+	// UntaggedUserSpace is true when a dereference reads user-space memory, e.g. +u8(...), through a pointer that
+	// the btf spec doesn't tag with __user. Fields then resolve to a kernel-space dereference instead.
+	UntaggedUserSpace bool
+}
+
+// OffsetResolutionError describes a dereference of a kprobe fetch arg whose offset doesn't match any member of the
+// btf type it dereferences. Position is the index in KprobeFetchArg.Derefs of the dereference, or -1 for the offset
+// of a symbol root. Path is the C representation of the field path resolved so far.
+type OffsetResolutionError struct {
+	FetchArg string
+	Position int
+	Offset   int64
+	TypeName string
+	Path     string
+	Err      error
+}
+
+func (e *OffsetResolutionError) Error() string {
+	return fmt.Sprintf("fetch arg %s: dereference %d at offset %d of %s after %s failed: %v", e.FetchArg, e.Position,
+		e.Offset, e.TypeName, e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error of the OffsetResolutionError.
+func (e *OffsetResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// SymbolizeFetchArg maps the offset chain of the given kprobe fetch arg, e.g. as parsed by ParseKprobeFetchArg,
+// back to a field path, based on the function prototype of the given symbol and the parameter or the return value
+// that the root register resolves to. Global variable roots, e.g. @jiffies+8, don't require the function prototype.
+// The offsets are matched against the members of the btf types that are dereferenced and nested structs, unions and
+// arrays are traversed until a member of the respective kind, namely a pointer for intermediate dereferences, and
+// a value compatible with the fetch arg type for the last one. When an offset doesn't match any member, an
+// OffsetResolutionError that wraps ErrOffsetNotFound is returned. Fetch args that start from tracing fs variables
+// that don't derive from the btf spec, e.g. $comm, or immediate values are returned without any fields.
+func (s *Spec) SymbolizeFetchArg(symbolName string, probeType ProbeType, fetchArg *KprobeFetchArg) (*SymbolizedFetchArg, error) {
+	symbolized := &SymbolizedFetchArg{
+		Name:       fetchArg.Name,
+		ParamIndex: -1,
+	}
+
+	derefs := fetchArg.Derefs
+	firstPosition := 0
+	firstSeparator := "->"
+
+	var rootType btf.Type
+	root := fetchArg.Root
+	switch {
+	case strings.HasPrefix(root, "@"):
+		var btfVar *btf.Var
+		if err := s.spec.TypeByName(root[1:], &btfVar); err != nil {
+			return nil, errors.Join(fmt.Errorf("getting global variable %s failed: %w", root[1:], ErrGlobalVariableNotFound), err)
+		}
+
+		symbolized.Param = root
+		symbolized.Path = btfVar.Name
+		// the symbol root reads the memory at the symbol address, thus it is the dereference of a pointer to it
+		rootType = &btf.Pointer{Target: btfVar.Type}
+		derefs = append([]KprobeDeref{{Offset: fetchArg.RootOffset}}, derefs...)
+		firstPosition = -1
+		firstSeparator = "."
+	case root == "$retval" || (probeType == ProbeTypeKRetProbe && root == s.regs.GetFuncReturnRegister()):
+		funcProto, err := s.funcProto(symbolName)
+		if err != nil {
+			return nil, err
+		}
+
+		symbolized.Param = "$retval"
+		symbolized.Path = "$retval"
+		rootType = funcProto.Return
+	case strings.HasPrefix(root, "$arg") || (probeType == ProbeTypeKProbe && strings.HasPrefix(root, "%")):
+		paramIndex, err := s.paramIndexOfRoot(root)
+		if err != nil {
+			return nil, err
+		}
+
+		funcProto, err := s.funcProto(symbolName)
+		if err != nil {
+			return nil, err
+		}
+
+		if paramIndex >= len(funcProto.Params) {
+			return nil, fmt.Errorf("getting func parameter %d of %s failed: %w", paramIndex, symbolName, ErrFuncParamNotFound)
+		}
+
+		symbolized.Param = funcProto.Params[paramIndex].Name
+		symbolized.ParamIndex = paramIndex
+		symbolized.Path = symbolized.Param
+		rootType = funcProto.Params[paramIndex].Type
+	default:
+		// the root doesn't derive from the btf spec
+		symbolized.Param = root
+		symbolized.Path = root
+		return symbolized, nil
+	}
+
+	var path strings.Builder
+	path.WriteString(symbolized.Path)
+
+	currentType := rootType
+	for i, deref := range derefs {
+		offsetErr := &OffsetResolutionError{
+			FetchArg: fetchArg.Name,
+			Position: firstPosition + i,
+			Offset:   deref.Offset,
+			TypeName: cTypeName(currentType, false),
+			Path:     path.String(),
+		}
+
+		ptr, ok := btf.UnderlyingType(currentType).(*btf.Pointer)
+		if !ok {
+			offsetErr.Err = fmt.Errorf("dereferenced type is not a pointer: %w", ErrOffsetNotFound)
+			return nil, offsetErr
+		}
+
+		accept := symbolizeAcceptIntermediate
+		if i == len(derefs)-1 {
+			accept = symbolizeAcceptLeaf(fetchArg.Type)
+		}
+
+		if deref.UserSpace && !isUserSpaceType(ptr.Target) {
+			symbolized.UntaggedUserSpace = true
+		}
+
+		steps, leafType, ok := membersAtOffset(ptr.Target, deref.Offset, accept)
+		if !ok {
+			offsetErr.TypeName = cTypeName(ptr.Target, false)
+			offsetErr.Err = ErrOffsetNotFound
+			return nil, offsetErr
+		}
+
+		separator := firstSeparator
+		for _, step := range steps {
+			symbolized.Fields = append(symbolized.Fields, step)
+			if strings.HasPrefix(step, "index:") {
+				path.WriteString("[" + strings.TrimPrefix(step, "index:") + "]")
+			} else {
+				path.WriteString(separator + step)
+			}
+			separator = "."
+		}
+
+		firstSeparator = "->"
+		currentType = leafType
+	}
+
+	symbolized.Path = path.String()
+	symbolized.TypeName = cTypeName(currentType, false)
+	return symbolized, nil
+}
+
+// funcProto returns the function prototype of the given symbol.
+func (s *Spec) funcProto(symbolName string) (*btf.FuncProto, error) {
+	var funcType *btf.Func
+	if err := s.spec.TypeByName(symbolName, &funcType); err != nil {
+		return nil, fmt.Errorf("getting func of %s failed: %w", symbolName, ErrSymbolNotFound)
+	}
+
+	funcProto, ok := funcType.Type.(*btf.FuncProto)
+	if !ok {
+		return nil, fmt.Errorf("btf func type is not a func proto %w", ErrFuncParamNotFound)
+	}
+
+	return funcProto, nil
+}
+
+// paramIndexOfRoot returns the index of the function parameter that the given root, namely $argN or a register,
+// resolves to.
+func (s *Spec) paramIndexOfRoot(root string) (int, error) {
+	if strings.HasPrefix(root, "$arg") {
+		argNumber, err := strconv.Atoi(strings.TrimPrefix(root, "$arg"))
+		if err != nil || argNumber < 1 {
+			return 0, fmt.Errorf("variable %s: %w", root, ErrUnsupportedFuncParamIndex)
+		}
+
+		return argNumber - 1, nil
+	}
+
+	for paramIndex := 0; ; paramIndex++ {
+		register, err := s.regs.GetFuncParamRegister(paramIndex)
+		if err != nil {
+			return 0, fmt.Errorf("register %s: %w", root, ErrUnsupportedFuncParamIndex)
+		}
+
+		if register == root {
+			return paramIndex, nil
+		}
+	}
+}
+
+// symbolizeAccept returns true if the given btf type, or the given bitfield layout of a member, can be the one
+// that a dereference resolves to.
+type symbolizeAccept func(typ btf.Type, memberBitfield *bitfield) bool
+
+// symbolizeAcceptIntermediate accepts the pointers that the intermediate dereferences of a fetch arg resolve to.
+func symbolizeAcceptIntermediate(typ btf.Type, memberBitfield *bitfield) bool {
+	_, isPointer := btf.UnderlyingType(typ).(*btf.Pointer)
+	return memberBitfield == nil && isPointer
+}
+
+// symbolizeAcceptLeaf returns a symbolizeAccept that accepts the values that the last dereference of a fetch arg
+// with the given type resolves to.
+func symbolizeAcceptLeaf(argType string) symbolizeAccept {
+	return func(typ btf.Type, memberBitfield *bitfield) bool {
+		underlyingType := btf.UnderlyingType(typ)
+		_, isArray := underlyingType.(*btf.Array)
+		_, isComposite := compositeMembers(underlyingType)
+
+		switch {
+		case memberBitfield != nil:
+			return memberBitfield.fetchArgType() == argType
+		case fetchesFromAddress(argType):
+			// the data are read from the address of the value, e.g. the characters of a char array or the ones
+			// a char pointer points to
+			return isArray || !isComposite
+		default:
+			return !isArray && !isComposite
+		}
+	}
+}
+
+// membersAtOffset returns the names of the members, starting from the given btf type, that reside at the given
+// offset in bytes and lead to a value that satisfies accept, along with the btf type of the latter. Nested structs,
+// unions and arrays are traversed, array elements are named as index:N and anonymous members are omitted.
+func membersAtOffset(typ btf.Type, offset int64, accept symbolizeAccept) ([]string, btf.Type, bool) {
+	underlyingType := btf.UnderlyingType(typ)
+
+	switch t := underlyingType.(type) {
+	case *btf.Struct, *btf.Union:
+		members, _ := compositeMembers(t)
+		for _, m := range members {
+			memberOffsetBytes, memberBitfield, err := memberOffset(m)
+			if err != nil {
+				continue
+			}
+
+			if memberBitfield != nil {
+				// bitfields can only be fetched from their container
+				if int64(memberOffsetBytes) == offset && accept(m.Type, memberBitfield) {
+					return []string{m.Name}, m.Type, true
+				}
+				continue
+			}
+
+			sizeBytes, err := btf.Sizeof(m.Type)
+			if err != nil || offset < int64(memberOffsetBytes) || offset >= int64(memberOffsetBytes)+int64(sizeBytes) {
+				continue
+			}
+
+			if int64(memberOffsetBytes) == offset && accept(m.Type, nil) {
+				return []string{m.Name}, m.Type, true
+			}
+
+			steps, leafType, ok := membersAtOffset(m.Type, offset-int64(memberOffsetBytes), accept)
+			if !ok {
+				// union members overlap, thus try the next one
+				continue
+			}
+
+			if m.Name != "" {
+				steps = append([]string{m.Name}, steps...)
+			}
+
+			return steps, leafType, true
+		}
+
+		return nil, nil, false
+	case *btf.Array:
+		elemSizeBytes, err := btf.Sizeof(t.Type)
+		if err != nil || elemSizeBytes == 0 || offset < 0 {
+			return nil, nil, false
+		}
+
+		index := offset / int64(elemSizeBytes)
+		if index >= int64(t.Nelems) {
+			return nil, nil, false
+		}
+
+		indexName := fmt.Sprintf("index:%d", index)
+		elemOffset := offset - index*int64(elemSizeBytes)
+		if elemOffset == 0 && accept(t.Type, nil) {
+			return []string{indexName}, t.Type, true
+		}
+
+		steps, leafType, ok := membersAtOffset(t.Type, elemOffset, accept)
+		if !ok {
+			return nil, nil, false
+		}
+
+		return append([]string{indexName}, steps...), leafType, true
+	default:
+		// the dereferenced value is the type itself, e.g. the characters a char pointer points to
+		if offset != 0 || !accept(typ, nil) {
+			return nil, nil, false
+		}
+
+		return nil, typ, true
+	}
+}
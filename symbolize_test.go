@@ -0,0 +1,188 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tkbtf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec_SymbolizeFetchArg(t *testing.T) {
+	tcs := []struct {
+		name         string
+		symbolName   string
+		probe        *Probe
+		expected     *SymbolizedFetchArg
+		rebuildParam bool
+	}{
+		{
+			name:       "param_pointer_chain",
+			symbolName: "test_function",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fi", "u32").FuncParamWithName("dentry_param", "d_inode", "i_ino"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "fi",
+				Param:      "dentry_param",
+				ParamIndex: 0,
+				Fields:     []string{"d_inode", "i_ino"},
+				Path:       "dentry_param->d_inode->i_ino",
+				TypeName:   "int",
+			},
+			rebuildParam: true,
+		},
+		{
+			name:       "nested_struct_string",
+			symbolName: "test_function",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fn", "string").FuncParamWithName("dentry_param", "d_name", "name"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "fn",
+				Param:      "dentry_param",
+				ParamIndex: 0,
+				Fields:     []string{"d_name", "name"},
+				Path:       "dentry_param->d_name.name",
+				TypeName:   "int8",
+			},
+			rebuildParam: true,
+		},
+		{
+			name:       "char_array_string",
+			symbolName: "test_function",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("fn", "string").FuncParamWithName("dentry_param", "d_iname"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "fn",
+				Param:      "dentry_param",
+				ParamIndex: 0,
+				Fields:     []string{"d_iname"},
+				Path:       "dentry_param->d_iname",
+				TypeName:   "char [32]",
+			},
+			rebuildParam: true,
+		},
+		{
+			name:       "anonymous_members",
+			symbolName: "test_function_with_ret",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("ppid", "s32").FuncParamWithName("tsk_param", "group_leader", "real_parent", "tgid"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "ppid",
+				Param:      "tsk_param",
+				ParamIndex: 2,
+				Fields:     []string{"group_leader", "real_parent", "tgid"},
+				Path:       "tsk_param->group_leader->real_parent->tgid",
+				TypeName:   "int",
+			},
+			rebuildParam: true,
+		},
+		{
+			name:       "return_value",
+			symbolName: "test_function_with_ret",
+			probe: NewKRetProbe().AddFetchArgs(
+				NewFetchArg("ri", "u64").FuncReturn("d_inode", "i_ino"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "ri",
+				Param:      "$retval",
+				ParamIndex: -1,
+				Fields:     []string{"d_inode", "i_ino"},
+				Path:       "$retval->d_inode->i_ino",
+				TypeName:   "int",
+			},
+		},
+		{
+			name:       "global_variable",
+			symbolName: "test_function",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("lvl", "u32").GlobalVariable("init_pid_ns", "level"),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "lvl",
+				Param:      "@init_pid_ns",
+				ParamIndex: -1,
+				Fields:     []string{"level"},
+				Path:       "init_pid_ns.level",
+				TypeName:   "unsigned int",
+			},
+		},
+		{
+			name:       "fetch_variable",
+			symbolName: "test_function",
+			probe: NewKProbe().AddFetchArgs(
+				NewFetchArg("comm", "string").Comm(),
+			),
+			expected: &SymbolizedFetchArg{
+				Name:       "comm",
+				Param:      "$comm",
+				ParamIndex: -1,
+				Path:       "$comm",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := generateBTFSpec()
+			spec.SetAnonymousMemberLookup(true)
+
+			symbol := NewSymbol(tc.symbolName).AddProbes(tc.probe)
+			require.NoError(t, spec.BuildSymbol(symbol))
+
+			fetchArg, err := ParseKprobeFetchArg(tc.probe.GetTracingEventProbe())
+			require.NoError(t, err)
+
+			symbolized, err := spec.SymbolizeFetchArg(tc.symbolName, tc.probe.GetType(), fetchArg)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, symbolized)
+
+			if !tc.rebuildParam {
+				return
+			}
+
+			// the symbolized field path must build to the same fetch arg
+			rebuiltProbe := NewKProbe().AddFetchArgs(
+				NewFetchArg(fetchArg.Name, fetchArg.Type).FuncParamWithName(symbolized.Param, symbolized.Fields...),
+			)
+			require.NoError(t, spec.BuildSymbol(NewSymbol(tc.symbolName).AddProbes(rebuiltProbe)))
+			require.Equal(t, tc.probe.GetTracingEventProbe(), rebuiltProbe.GetTracingEventProbe())
+		})
+	}
+}
+
+func TestSpec_SymbolizeFetchArgOffsetNotFound(t *testing.T) {
+	spec := generateBTFSpec()
+
+	fetchArg, err := ParseKprobeFetchArg("fi=+4(+48(%di)):u32")
+	require.NoError(t, err)
+
+	_, err = spec.SymbolizeFetchArg("test_function", ProbeTypeKProbe, fetchArg)
+	require.ErrorIs(t, err, ErrOffsetNotFound)
+
+	var offsetErr *OffsetResolutionError
+	require.True(t, errors.As(err, &offsetErr))
+	require.Equal(t, 1, offsetErr.Position)
+	require.Equal(t, int64(4), offsetErr.Offset)
+	require.Equal(t, "struct inode", offsetErr.TypeName)
+	require.Equal(t, "dentry_param->d_inode", offsetErr.Path)
+}